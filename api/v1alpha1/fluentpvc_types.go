@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -10,9 +11,11 @@ import (
 // FluentPVCSpec defines the desired state of FluentPVC
 type FluentPVCSpec struct {
 	// PVC spec template to inject into pod manifests.
+	// accessModes defaults to ["ReadWriteOnce"], and storageClassName defaults to the default StorageClass.
 	//+kubebuilder:validation:Required
 	PVCSpecTemplate corev1.PersistentVolumeClaimSpec `json:"pvcSpecTemplate"`
 	// Job template to finalize PVCs.
+	// template.spec.restartPolicy defaults to "Never".
 	//+kubebuilder:validation:Required
 	PVCFinalizerJobSpecTemplate batchv1.JobSpec `json:"pvcFinalizerJobSpecTemplate"`
 	// Name of the Volume to mount the PVC.
@@ -36,11 +39,299 @@ type FluentPVCSpec struct {
 	// Common volumeMounts to inject into all containers.
 	//+optional
 	CommonVolumeMounts []corev1.VolumeMount `json:"commonVolumeMounts,omitempty"`
-	// Delete the pod if the sidecar container termination is detected.
+	// Delete the pod if the sidecar container termination is detected. Defaults to true.
+	//+kubebuilder:default=true
+	//+optional
+	DeletePodIfSidecarContainerTerminationDetected *bool `json:"deletePodIfSidecarContainerTerminationDetected,omitempty"`
+	// Policy to reattach the PVC of a predecessor pod to a replacement pod.
+	// "Never" always provisions a new PVC for each pod.
+	// "StatefulSetOrdinal" reattaches the PVC that is out of use and not finalized yet
+	// if the predecessor pod has the same StatefulSet owner and ordinal.
+	//+kubebuilder:validation:Enum=Never;StatefulSetOrdinal
+	//+kubebuilder:default=Never
+	//+optional
+	ReattachPolicy FluentPVCReattachPolicy `json:"reattachPolicy,omitempty"`
+	// Mode to provision PVCs.
+	// "Webhook" creates the PVC and the FluentPVCBinding in the pod admission webhook.
+	// "Controller" only injects the claim name into the pod in the webhook, and then
+	// the PVC and the FluentPVCBinding are created by the controller after the pod is created.
+	// "Ephemeral" injects a generic ephemeral volume into the pod, and then the controller adopts
+	// the PVC created by Kubernetes so that it is finalized after the pod is deleted.
+	//+kubebuilder:validation:Enum=Webhook;Controller;Ephemeral
+	//+kubebuilder:default=Webhook
+	//+optional
+	ProvisioningMode FluentPVCProvisioningMode `json:"provisioningMode,omitempty"`
+	// Template of the name prefix for PVCs, FluentPVCBindings and finalizer Jobs.
+	// The template is rendered by text/template with .FluentPVCName, .PodName, .PodGenerateName and .Namespace.
+	// The rendered prefix is sanitized and truncated so that the name with hash suffixes is a DNS_LABEL.
+	// The hash suffixes are computed from the spec, the namespace and the pod name or generateName.
+	// Defaults to the FluentPVC name.
+	//+optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	// Strategy to update the sidecar containers of the existing pods when the pod template parts of the spec
+	// (sidecarContainerTemplate, commonEnvs, commonVolumes, commonVolumeMounts, podTemplateOverrides, pvcVolumeName and pvcVolumeMountPath) are changed.
+	//+optional
+	SidecarUpdateStrategy FluentPVCSidecarUpdateStrategy `json:"sidecarUpdateStrategy,omitempty"`
+	// Strategies to merge the injected items into pods when the items of the same names already exist.
+	//+optional
+	MergeStrategies FluentPVCMergeStrategies `json:"mergeStrategies,omitempty"`
+	// Containers to inject the common envs, the common volumeMounts and the volumeMount of the PVC into.
+	// All containers are selected if not specified. The sidecar container is always selected.
+	// The selector is not applied to the finalizer Job, whose containers are all injected.
+	//+optional
+	TargetContainers FluentPVCTargetContainers `json:"targetContainers,omitempty"`
+	// SubPaths of the volumeMount of the PVC for each container name.
+	// The root of the PVC is mounted into the containers not specified.
+	//+optional
+	PVCVolumeMountSubPaths map[string]string `json:"pvcVolumeMountSubPaths,omitempty"`
+	// Inject the common envs, the common volumeMounts and the volumeMount of the PVC into the init containers
+	// selected by targetContainers too.
+	//+optional
+	InjectInitContainers bool `json:"injectInitContainers,omitempty"`
+	// Inject the common envs and the volumeMounts of the volumes that the pod has into the ephemeral containers
+	// added to the pod (e.g. by `kubectl debug`).
+	//+optional
+	InjectEphemeralContainers bool `json:"injectEphemeralContainers,omitempty"`
+	// Overrides of the pod to merge into pods when the sidecar container is injected.
+	// The conflicts with the existing items are merged by mergeStrategies.podTemplateOverrides.
+	//+optional
+	PodTemplateOverrides FluentPVCPodTemplateOverrides `json:"podTemplateOverrides,omitempty"`
+	// Namespaces of the pods allowed to use the FluentPVC. All namespaces are allowed if not specified.
+	// The FluentPVC is validated against these namespaces if specified.
+	//+optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// Maximum number of the PVCs provisioned by the FluentPVC in each namespace. The live FluentPVCBindings are
+	// counted, and the pods which need a new PVC are denied when the number reaches the maximum. The limit is
+	// best-effort against the concurrent admissions, so use ResourceQuotas for the hard limits.
+	//+kubebuilder:validation:Minimum=1
+	//+optional
+	MaxPVCsPerNamespace *int32 `json:"maxPVCsPerNamespace,omitempty"`
+	// Maximum total storage requests of the PVCs provisioned by the FluentPVC in each namespace. The PVCs of
+	// the live FluentPVCBindings are counted, and the pods which need a new PVC are denied when the total exceeds it.
+	// The limit is best-effort against the concurrent admissions, so use ResourceQuotas for the hard limits.
+	//+optional
+	MaxTotalStoragePerNamespace *resource.Quantity `json:"maxTotalStoragePerNamespace,omitempty"`
+	// Policy when the pod cannot be mutated because of errors (e.g. failures of API requests).
+	// "Fail" rejects the pod. "Ignore" admits the pod with an emptyDir volume instead of the PVC, or unmodified if
+	// even the emptyDir volume cannot be injected, and labels it with "fluent-pvc-operator.tech.zozo.com/degraded".
+	// The logs written to the emptyDir volume are not finalized. The pods are rejected while the pod webhook is
+	// unavailable unless they are labeled with "fluent-pvc-operator.tech.zozo.com/admission-failure-policy: Ignore".
+	//+kubebuilder:validation:Enum=Fail;Ignore
+	//+kubebuilder:default=Fail
+	//+optional
+	AdmissionFailurePolicy FluentPVCAdmissionFailurePolicy `json:"admissionFailurePolicy,omitempty"`
+	// Handling of the pods stranded on failed nodes.
+	//+optional
+	NodeFailureHandling FluentPVCNodeFailureHandling `json:"nodeFailureHandling,omitempty"`
+	// Archive the PVC as a VolumeSnapshot before it is deleted. The VolumeSnapshot is created after the finalizer
+	// Job is succeeded, and the PVC is deleted after the VolumeSnapshot is ready to use.
+	//+optional
+	SnapshotBeforeDelete *FluentPVCSnapshotBeforeDelete `json:"snapshotBeforeDelete,omitempty"`
+	// Mode to finalize PVCs.
+	// "Direct" mounts the PVC into the finalizer Job.
+	// "Snapshot" takes a VolumeSnapshot of the PVC as soon as the FluentPVCBinding becomes OutOfUse, restores it
+	// into a new PVC for the finalizer Job, and then releases the original PVC immediately.
+	//+kubebuilder:validation:Enum=Direct;Snapshot
+	//+kubebuilder:default=Direct
+	//+optional
+	FinalizationMode FluentPVCFinalizationMode `json:"finalizationMode,omitempty"`
+	// Configurations of the "Snapshot" finalizationMode. It is required if finalizationMode is "Snapshot".
+	//+optional
+	SnapshotFinalization *FluentPVCSnapshotFinalization `json:"snapshotFinalization,omitempty"`
+	// Policy to finalize the PVC whose PersistentVolume has the zone topology when no schedulable nodes are in the zone.
+	// "Wait" waits for the zone to be available with the ZoneUnavailable condition of the FluentPVCBinding.
+	// "SnapshotAndRestore" finalizes the PVC in the same way as the "Snapshot" finalizationMode, so that the PVC
+	// restored from the VolumeSnapshot is provisioned in an available zone. snapshotFinalization is required.
+	//+kubebuilder:validation:Enum=Wait;SnapshotAndRestore
+	//+kubebuilder:default=Wait
+	//+optional
+	ZoneUnavailablePolicy FluentPVCZoneUnavailablePolicy `json:"zoneUnavailablePolicy,omitempty"`
+}
+
+type FluentPVCSnapshotFinalization struct {
+	// Name of the VolumeSnapshotClass to take the VolumeSnapshot of the PVC with.
+	//+kubebuilder:validation:Required
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName"`
+	// Name of the StorageClass of the PVC restored from the VolumeSnapshot.
+	// Defaults to the StorageClass of the original PVC.
+	//+optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+type FluentPVCSnapshotBeforeDelete struct {
+	// Name of the VolumeSnapshotClass to create the VolumeSnapshot with.
 	//+kubebuilder:validation:Required
-	DeletePodIfSidecarContainerTerminationDetected bool `json:"deletePodIfSidecarContainerTerminationDetected,omitempty"`
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName"`
+	// Days to retain the VolumeSnapshot. The VolumeSnapshot is deleted after the retention. Defaults to 7.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=7
+	//+optional
+	RetentionDays int32 `json:"retentionDays,omitempty"`
+}
+
+type FluentPVCNodeFailureHandling struct {
+	// Policy for the pods stranded on failed nodes.
+	// "None" leaves the pods to Kubernetes, and the PVC is finalized after the pod is deleted by hand or by
+	// the pod garbage collector. "ForceDeletePod" regards the pod as gone after gracePeriodSeconds, and
+	// force-deletes it so that the volume can be detached and the PVC is finalized on another node.
+	//+kubebuilder:validation:Enum=None;ForceDeletePod
+	//+kubebuilder:default=None
+	//+optional
+	Policy FluentPVCNodeFailurePolicy `json:"policy,omitempty"`
+	// Seconds to wait after the node of the pod becomes NotReady before the pod is regarded as gone when
+	// the policy is "ForceDeletePod". The pod is regarded as gone immediately if the node is deleted. Defaults to 300.
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:default=300
+	//+optional
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// Delete the VolumeAttachments of the PVC left on the lost nodes when the finalizer Job cannot attach
+	// the volume because of the Multi-Attach error. The node is confirmed lost in the same way as the pods.
+	// It is only for the volumes that the storage backend can safely detach from the lost nodes.
+	//+optional
+	ForceDetachVolumes bool `json:"forceDetachVolumes,omitempty"`
+}
+
+type FluentPVCPodTemplateOverrides struct {
+	// Labels and annotations to merge into the pod metadata.
+	//+optional
+	Metadata FluentPVCPodTemplateOverridesMetadata `json:"metadata,omitempty"`
+	// Pod security context to merge into the pod. Only the specified fields are merged.
+	//+optional
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+	// Tolerations to merge into the pod. Tolerations of the same key and effect are regarded as the same item.
+	//+optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Resource requests and limits to merge into the containers of the names (e.g. more memory for the
+	// containers that write to the PVC). Only the specified resource names are merged.
+	//+optional
+	ContainerResources map[string]corev1.ResourceRequirements `json:"containerResources,omitempty"`
+}
+
+type FluentPVCPodTemplateOverridesMetadata struct {
+	// Labels to merge into the pod.
+	//+optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations to merge into the pod.
+	//+optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type FluentPVCTargetContainers struct {
+	// Names of the containers to select.
+	//+optional
+	Names []string `json:"names,omitempty"`
+	// Regular expression to select the containers whose names fully match it.
+	//+optional
+	NameRegex string `json:"nameRegex,omitempty"`
+	// Names of the containers not to select. It takes precedence over names and nameRegex.
+	//+optional
+	ExcludeNames []string `json:"excludeNames,omitempty"`
+}
+
+type FluentPVCMergeStrategies struct {
+	// Strategy to merge the sidecar container.
+	//+kubebuilder:default=Replace
+	//+optional
+	Containers FluentPVCMergeStrategy `json:"containers,omitempty"`
+	// Strategy to merge the common volumes and the volume of the PVC.
+	//+kubebuilder:default=Replace
+	//+optional
+	Volumes FluentPVCMergeStrategy `json:"volumes,omitempty"`
+	// Strategy to merge the common volumeMounts and the volumeMount of the PVC.
+	//+kubebuilder:default=Replace
+	//+optional
+	VolumeMounts FluentPVCMergeStrategy `json:"volumeMounts,omitempty"`
+	// Strategy to merge the common envs.
+	//+kubebuilder:default=Replace
+	//+optional
+	Envs FluentPVCMergeStrategy `json:"envs,omitempty"`
+	// Strategy to merge podTemplateOverrides.
+	//+kubebuilder:default=Replace
+	//+optional
+	PodTemplateOverrides FluentPVCMergeStrategy `json:"podTemplateOverrides,omitempty"`
 }
 
+// FluentPVCMergeStrategy defines how to merge an injected item into the pod when an item of the same name exists.
+// "Replace" replaces the existing item with the injected item.
+// "KeepExisting" keeps the existing item and discards the injected item.
+// "Fail" denies the pod.
+//+kubebuilder:validation:Enum=Replace;KeepExisting;Fail
+type FluentPVCMergeStrategy string
+
+const (
+	FluentPVCMergeStrategyReplace      FluentPVCMergeStrategy = "Replace"
+	FluentPVCMergeStrategyKeepExisting FluentPVCMergeStrategy = "KeepExisting"
+	FluentPVCMergeStrategyFail         FluentPVCMergeStrategy = "Fail"
+)
+
+type FluentPVCSidecarUpdateStrategy struct {
+	// Type of the strategy.
+	// "OnDelete" updates the sidecar containers only when the pods are recreated by someone else.
+	// "RollingRestart" restarts the Deployments, StatefulSets and DaemonSets that own the pods with stale sidecar containers.
+	// Each restart follows the rolling update strategy of the workload, and is not started while any
+	// PodDisruptionBudgets of the pods do not allow disruptions, the unavailable pods of the workload reach
+	// its maxUnavailable, or the update strategy of the workload is OnDelete.
+	//+kubebuilder:validation:Enum=OnDelete;RollingRestart
+	//+kubebuilder:default=OnDelete
+	//+optional
+	Type FluentPVCSidecarUpdateStrategyType `json:"type,omitempty"`
+	// Max number of workloads restarted at the same time by "RollingRestart". Defaults to 1.
+	// The pods of each workload are replaced within maxUnavailable of its update strategy.
+	//+kubebuilder:validation:Minimum=1
+	//+optional
+	MaxConcurrentRestarts int32 `json:"maxConcurrentRestarts,omitempty"`
+}
+
+type FluentPVCSidecarUpdateStrategyType string
+
+const (
+	FluentPVCSidecarUpdateStrategyOnDelete       FluentPVCSidecarUpdateStrategyType = "OnDelete"
+	FluentPVCSidecarUpdateStrategyRollingRestart FluentPVCSidecarUpdateStrategyType = "RollingRestart"
+)
+
+type FluentPVCReattachPolicy string
+
+const (
+	FluentPVCReattachPolicyNever              FluentPVCReattachPolicy = "Never"
+	FluentPVCReattachPolicyStatefulSetOrdinal FluentPVCReattachPolicy = "StatefulSetOrdinal"
+)
+
+type FluentPVCProvisioningMode string
+
+const (
+	FluentPVCProvisioningModeWebhook    FluentPVCProvisioningMode = "Webhook"
+	FluentPVCProvisioningModeController FluentPVCProvisioningMode = "Controller"
+	FluentPVCProvisioningModeEphemeral  FluentPVCProvisioningMode = "Ephemeral"
+)
+
+type FluentPVCFinalizationMode string
+
+const (
+	FluentPVCFinalizationModeDirect   FluentPVCFinalizationMode = "Direct"
+	FluentPVCFinalizationModeSnapshot FluentPVCFinalizationMode = "Snapshot"
+)
+
+type FluentPVCAdmissionFailurePolicy string
+
+const (
+	FluentPVCAdmissionFailurePolicyFail   FluentPVCAdmissionFailurePolicy = "Fail"
+	FluentPVCAdmissionFailurePolicyIgnore FluentPVCAdmissionFailurePolicy = "Ignore"
+)
+
+type FluentPVCNodeFailurePolicy string
+
+const (
+	FluentPVCNodeFailurePolicyNone           FluentPVCNodeFailurePolicy = "None"
+	FluentPVCNodeFailurePolicyForceDeletePod FluentPVCNodeFailurePolicy = "ForceDeletePod"
+)
+
+type FluentPVCZoneUnavailablePolicy string
+
+const (
+	FluentPVCZoneUnavailablePolicyWait               FluentPVCZoneUnavailablePolicy = "Wait"
+	FluentPVCZoneUnavailablePolicySnapshotAndRestore FluentPVCZoneUnavailablePolicy = "SnapshotAndRestore"
+)
+
 // FluentPVCStatus defines the observed state of FluentPVC
 type FluentPVCStatus struct {
 	// Conditions is an array of conditions.
@@ -50,6 +341,33 @@ type FluentPVCStatus struct {
 	//+listType=map
 	//+listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Name of the FluentPVCRevision of the current spec.
+	//+optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// Progress of the sidecar update by sidecarUpdateStrategy.
+	//+optional
+	SidecarUpdate *FluentPVCSidecarUpdateStatus `json:"sidecarUpdate,omitempty"`
+	// Pods admitted without the PVC, in the format of '<namespace>/<name>'. The pods labeled as degraded by
+	// the "Ignore" admissionFailurePolicy and the pods admitted without the sidecar container (e.g. while
+	// the pod webhook is unavailable) are included.
+	//+optional
+	DegradedPods []string `json:"degradedPods,omitempty"`
+}
+
+type FluentPVCSidecarUpdateStatus struct {
+	// Name of the FluentPVCRevision that the sidecar containers are updated to.
+	Revision string `json:"revision"`
+	// Number of the pods whose sidecar containers are up to date.
+	UpdatedPods int32 `json:"updatedPods"`
+	// Number of the pods whose sidecar containers are stale.
+	StalePods int32 `json:"stalePods"`
+	// Workloads that are being restarted, in the format of '<kind>/<namespace>/<name>'.
+	//+optional
+	RestartingWorkloads []string `json:"restartingWorkloads,omitempty"`
+	// Workloads that cannot be restarted, in the format of '<kind>/<namespace>/<name>'.
+	// Pods without any supported owners and workloads whose PodDisruptionBudgets do not allow disruptions are included.
+	//+optional
+	BlockedWorkloads []string `json:"blockedWorkloads,omitempty"`
 }
 
 // FluentPVC is the Schema for the fluentpvcs API
@@ -82,6 +400,9 @@ type FluentPVCBindingSpec struct {
 	// Pod Name to bind.
 	//+kubebuilder:validation:Required
 	Pod ObjectIdentity `json:"pod"`
+	// FluentPVCRevision used to inject the PVC into the pod and to finalize the PVC.
+	//+optional
+	FluentPVCRevision *ObjectIdentity `json:"fluentPVCRevision,omitempty"`
 }
 
 type ObjectIdentity struct {
@@ -96,26 +417,30 @@ type ObjectIdentity struct {
 type FluentPVCBindingConditionType string
 
 const (
-	FluentPVCBindingConditionReady                 FluentPVCBindingConditionType = "Ready"
-	FluentPVCBindingConditionOutOfUse              FluentPVCBindingConditionType = "OutOfUse"
-	FluentPVCBindingConditionFinalizerJobApplied   FluentPVCBindingConditionType = "FinalizerJobApplied"
-	FluentPVCBindingConditionFinalizerJobSucceeded FluentPVCBindingConditionType = "FinalizerJobSucceeded"
-	FluentPVCBindingConditionFinalizerJobFailed    FluentPVCBindingConditionType = "FinalizerJobFailed"
-	FluentPVCBindingConditionUnknown               FluentPVCBindingConditionType = "Unknown"
-	FluentPVCBindingConditionPodMissing            FluentPVCBindingConditionType = "PodMissing"
+	FluentPVCBindingConditionReady                     FluentPVCBindingConditionType = "Ready"
+	FluentPVCBindingConditionOutOfUse                  FluentPVCBindingConditionType = "OutOfUse"
+	FluentPVCBindingConditionFinalizerJobApplied       FluentPVCBindingConditionType = "FinalizerJobApplied"
+	FluentPVCBindingConditionFinalizerJobSucceeded     FluentPVCBindingConditionType = "FinalizerJobSucceeded"
+	FluentPVCBindingConditionFinalizerJobFailed        FluentPVCBindingConditionType = "FinalizerJobFailed"
+	FluentPVCBindingConditionUnknown                   FluentPVCBindingConditionType = "Unknown"
+	FluentPVCBindingConditionPodMissing                FluentPVCBindingConditionType = "PodMissing"
+	FluentPVCBindingConditionFinalizerJobMultiAttached FluentPVCBindingConditionType = "FinalizerJobMultiAttached"
+	FluentPVCBindingConditionZoneUnavailable           FluentPVCBindingConditionType = "ZoneUnavailable"
 )
 
 type FluentPVCBindingPhase string
 
 const (
-	FluentPVCBindingPhasePending               FluentPVCBindingPhase = "Pending"
-	FluentPVCBindingPhaseReady                 FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionReady)
-	FluentPVCBindingPhaseOutOfUse              FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionOutOfUse)
-	FluentPVCBindingPhaseFinalizerJobApplied   FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobApplied)
-	FluentPVCBindingPhaseFinalizerJobSucceeded FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobSucceeded)
-	FluentPVCBindingPhaseFinalizerJobFailed    FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobFailed)
-	FluentPVCBindingPhaseUnknown               FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionUnknown)
-	FluentPVCBindingPhasePodMissing            FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionPodMissing)
+	FluentPVCBindingPhasePending                   FluentPVCBindingPhase = "Pending"
+	FluentPVCBindingPhaseReady                     FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionReady)
+	FluentPVCBindingPhaseOutOfUse                  FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionOutOfUse)
+	FluentPVCBindingPhaseFinalizerJobApplied       FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobApplied)
+	FluentPVCBindingPhaseFinalizerJobSucceeded     FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobSucceeded)
+	FluentPVCBindingPhaseFinalizerJobFailed        FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobFailed)
+	FluentPVCBindingPhaseUnknown                   FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionUnknown)
+	FluentPVCBindingPhasePodMissing                FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionPodMissing)
+	FluentPVCBindingPhaseFinalizerJobMultiAttached FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionFinalizerJobMultiAttached)
+	FluentPVCBindingPhaseZoneUnavailable           FluentPVCBindingPhase = FluentPVCBindingPhase(FluentPVCBindingConditionZoneUnavailable)
 )
 
 // FluentPVCStatus defines the observed state of FluentPVC
@@ -129,6 +454,72 @@ type FluentPVCBindingStatus struct {
 
 	// Phase is the latest condition.
 	Phase FluentPVCBindingPhase `json:"phase,omitempty"`
+
+	// Last action requested by the action annotation.
+	//+optional
+	LastAction *FluentPVCBindingActionRecord `json:"lastAction,omitempty"`
+
+	// Name of the failed node where the pod is lost. The finalizer job is not scheduled on the node.
+	//+optional
+	LostNodeName string `json:"lostNodeName,omitempty"`
+
+	// Result reported by the finalizer job as the JSON termination message of the container.
+	//+optional
+	FinalizerJobResult *FluentPVCBindingFinalizerJobResult `json:"finalizerJobResult,omitempty"`
+
+	// PVC restored from the VolumeSnapshot of the PVC to be finalized instead of the PVC.
+	// The PVC is already released if it is set. See FluentPVCFinalizationModeSnapshot.
+	//+optional
+	FinalizerPVC *ObjectIdentity `json:"finalizerPVC,omitempty"`
+}
+
+// FluentPVCBindingFinalizerJobResult is the result of the finalizer job. The container of the finalizer job
+// reports it by writing the JSON (e.g. {"bytesShipped":1024,"records":10,"destinationURI":"s3://bucket/key","checksum":"sha256:..."})
+// to the terminationMessagePath.
+type FluentPVCBindingFinalizerJobResult struct {
+	// Bytes shipped to the destination.
+	//+optional
+	BytesShipped *int64 `json:"bytesShipped,omitempty"`
+	// Number of records shipped to the destination.
+	//+optional
+	Records *int64 `json:"records,omitempty"`
+	// URI of the destination.
+	//+optional
+	DestinationURI string `json:"destinationURI,omitempty"`
+	// Checksum of the shipped data.
+	//+optional
+	Checksum string `json:"checksum,omitempty"`
+	// Name of the pod that reported the result.
+	//+optional
+	PodName string `json:"podName,omitempty"`
+	// Name of the container that reported the result.
+	//+optional
+	ContainerName string `json:"containerName,omitempty"`
+	// Time when the container that reported the result is finished.
+	//+optional
+	FinishedAt metav1.Time `json:"finishedAt,omitempty"`
+}
+
+// FluentPVCBindingAction is a manual remediation requested by the action annotation.
+// "retry" clears the Unknown condition so that the controllers evaluate the FluentPVCBinding again.
+// "rerun-finalizer" deletes the finalizer jobs and clears the Unknown and finalizer job conditions, so that a new finalizer job is applied.
+// "release" removes the finalizers of the PVC and the FluentPVCBinding, and deletes the FluentPVCBinding. The PVC is kept.
+type FluentPVCBindingAction string
+
+const (
+	FluentPVCBindingActionRetry          FluentPVCBindingAction = "retry"
+	FluentPVCBindingActionRerunFinalizer FluentPVCBindingAction = "rerun-finalizer"
+	FluentPVCBindingActionRelease        FluentPVCBindingAction = "release"
+)
+
+type FluentPVCBindingActionRecord struct {
+	// Action requested.
+	Action FluentPVCBindingAction `json:"action"`
+	// Name of the user who requested the action.
+	//+optional
+	RequestedBy string `json:"requestedBy,omitempty"`
+	// Time when the action is processed.
+	Time metav1.Time `json:"time"`
 }
 
 //+kubebuilder:object:root=true
@@ -154,9 +545,36 @@ type FluentPVCBindingList struct {
 	Items           []FluentPVCBinding `json:"items"`
 }
 
+// FluentPVCRevision is an immutable snapshot of a FluentPVC spec.
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="REVISION",type="integer",JSONPath=".revision"
+//+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+type FluentPVCRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Snapshot of the FluentPVC spec.
+	//+kubebuilder:validation:Required
+	Spec FluentPVCSpec `json:"spec"`
+	// Revision number of the spec. It is incremented every time the FluentPVC spec is changed.
+	// The revision number is bumped to the latest when the FluentPVC spec is reverted to this spec.
+	//+kubebuilder:validation:Required
+	Revision int64 `json:"revision"`
+}
+
+// FluentPVCRevisionList contains a list of FluentPVCRevision
+//+kubebuilder:object:root=true
+type FluentPVCRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FluentPVCRevision `json:"items"`
+}
+
 func init() {
 	SchemeBuilder.Register(
 		&FluentPVC{}, &FluentPVCList{},
 		&FluentPVCBinding{}, &FluentPVCBindingList{},
+		&FluentPVCRevision{}, &FluentPVCRevisionList{},
 	)
 }
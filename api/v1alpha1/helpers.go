@@ -36,6 +36,14 @@ func (b *FluentPVCBinding) IsConditionPodMissing() bool {
 	return meta.IsStatusConditionTrue(b.Status.Conditions, string(FluentPVCBindingConditionPodMissing))
 }
 
+func (b *FluentPVCBinding) IsConditionFinalizerJobMultiAttached() bool {
+	return meta.IsStatusConditionTrue(b.Status.Conditions, string(FluentPVCBindingConditionFinalizerJobMultiAttached))
+}
+
+func (b *FluentPVCBinding) IsConditionZoneUnavailable() bool {
+	return meta.IsStatusConditionTrue(b.Status.Conditions, string(FluentPVCBindingConditionZoneUnavailable))
+}
+
 func (b *FluentPVCBinding) SetConditionReady(reason, message string) {
 	b.setConditionTrue(FluentPVCBindingConditionReady, reason, message)
 }
@@ -64,6 +72,14 @@ func (b *FluentPVCBinding) SetConditionPodMissing(reason, message string) {
 	b.setConditionTrue(FluentPVCBindingConditionPodMissing, reason, message)
 }
 
+func (b *FluentPVCBinding) SetConditionFinalizerJobMultiAttached(reason, message string) {
+	b.setConditionTrue(FluentPVCBindingConditionFinalizerJobMultiAttached, reason, message)
+}
+
+func (b *FluentPVCBinding) SetConditionZoneUnavailable(reason, message string) {
+	b.setConditionTrue(FluentPVCBindingConditionZoneUnavailable, reason, message)
+}
+
 func (b *FluentPVCBinding) SetConditionNotReady(reason, message string) {
 	b.setConditionFalse(FluentPVCBindingConditionReady, reason, message)
 }
@@ -88,6 +104,14 @@ func (b *FluentPVCBinding) SetConditionNotUnknown(reason, message string) {
 	b.setConditionFalse(FluentPVCBindingConditionUnknown, reason, message)
 }
 
+func (b *FluentPVCBinding) SetConditionNotFinalizerJobMultiAttached(reason, message string) {
+	b.setConditionFalse(FluentPVCBindingConditionFinalizerJobMultiAttached, reason, message)
+}
+
+func (b *FluentPVCBinding) SetConditionNotZoneUnavailable(reason, message string) {
+	b.setConditionFalse(FluentPVCBindingConditionZoneUnavailable, reason, message)
+}
+
 func (b *FluentPVCBinding) setConditionTrue(t FluentPVCBindingConditionType, reason, message string) {
 	b.setCondition(t, metav1.ConditionTrue, reason, message)
 }
@@ -125,6 +149,14 @@ func (b *FluentPVCBinding) resetPhase() {
 	b.Status.Phase = FluentPVCBindingPhase(conditions[0].Type)
 }
 
+func (b *FluentPVCBinding) SetLastAction(action FluentPVCBindingAction, requestedBy string) {
+	b.Status.LastAction = &FluentPVCBindingActionRecord{
+		Action:      action,
+		RequestedBy: requestedBy,
+		Time:        metav1.Now(),
+	}
+}
+
 func (b *FluentPVCBinding) SetFluentPVC(fpvc *FluentPVC) {
 	b.Spec.FluentPVC = b.toObjectIdentity(&fpvc.ObjectMeta)
 }
@@ -136,6 +168,11 @@ func (b *FluentPVCBinding) SetPod(pod *corev1.Pod) {
 	b.Spec.Pod = b.toObjectIdentity(&pod.ObjectMeta)
 }
 
+func (b *FluentPVCBinding) SetFluentPVCRevision(r *FluentPVCRevision) {
+	identity := b.toObjectIdentity(&r.ObjectMeta)
+	b.Spec.FluentPVCRevision = &identity
+}
+
 func (b *FluentPVCBinding) toObjectIdentity(o *metav1.ObjectMeta) ObjectIdentity {
 	return ObjectIdentity{
 		Name: o.Name,
@@ -158,3 +195,12 @@ func (b *FluentPVCBinding) IsBindingPod(pod *corev1.Pod) bool {
 func (b *FluentPVCBinding) IsBindingPVC(pvc *corev1.PersistentVolumeClaim) bool {
 	return pvc.UID == b.Spec.PVC.UID
 }
+
+func (b *FluentPVCBinding) SetFinalizerPVC(pvc *corev1.PersistentVolumeClaim) {
+	identity := b.toObjectIdentity(&pvc.ObjectMeta)
+	b.Status.FinalizerPVC = &identity
+}
+
+func (b *FluentPVCBinding) IsFinalizerPVC(pvc *corev1.PersistentVolumeClaim) bool {
+	return b.Status.FinalizerPVC != nil && b.Status.FinalizerPVC.UID == pvc.UID
+}
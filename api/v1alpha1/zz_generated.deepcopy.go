@@ -42,7 +42,7 @@ func (in *FluentPVCBinding) DeepCopyInto(out *FluentPVCBinding) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -64,6 +64,48 @@ func (in *FluentPVCBinding) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCBindingActionRecord) DeepCopyInto(out *FluentPVCBindingActionRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCBindingActionRecord.
+func (in *FluentPVCBindingActionRecord) DeepCopy() *FluentPVCBindingActionRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCBindingActionRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCBindingFinalizerJobResult) DeepCopyInto(out *FluentPVCBindingFinalizerJobResult) {
+	*out = *in
+	if in.BytesShipped != nil {
+		in, out := &in.BytesShipped, &out.BytesShipped
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Records != nil {
+		in, out := &in.Records, &out.Records
+		*out = new(int64)
+		**out = **in
+	}
+	in.FinishedAt.DeepCopyInto(&out.FinishedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCBindingFinalizerJobResult.
+func (in *FluentPVCBindingFinalizerJobResult) DeepCopy() *FluentPVCBindingFinalizerJobResult {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCBindingFinalizerJobResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FluentPVCBindingList) DeepCopyInto(out *FluentPVCBindingList) {
 	*out = *in
@@ -102,6 +144,11 @@ func (in *FluentPVCBindingSpec) DeepCopyInto(out *FluentPVCBindingSpec) {
 	out.FluentPVC = in.FluentPVC
 	out.PVC = in.PVC
 	out.Pod = in.Pod
+	if in.FluentPVCRevision != nil {
+		in, out := &in.FluentPVCRevision, &out.FluentPVCRevision
+		*out = new(ObjectIdentity)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCBindingSpec.
@@ -124,6 +171,21 @@ func (in *FluentPVCBindingStatus) DeepCopyInto(out *FluentPVCBindingStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastAction != nil {
+		in, out := &in.LastAction, &out.LastAction
+		*out = new(FluentPVCBindingActionRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FinalizerJobResult != nil {
+		in, out := &in.FinalizerJobResult, &out.FinalizerJobResult
+		*out = new(FluentPVCBindingFinalizerJobResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FinalizerPVC != nil {
+		in, out := &in.FinalizerPVC, &out.FinalizerPVC
+		*out = new(ObjectIdentity)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCBindingStatus.
@@ -168,6 +230,238 @@ func (in *FluentPVCList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCMergeStrategies) DeepCopyInto(out *FluentPVCMergeStrategies) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCMergeStrategies.
+func (in *FluentPVCMergeStrategies) DeepCopy() *FluentPVCMergeStrategies {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCMergeStrategies)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCNodeFailureHandling) DeepCopyInto(out *FluentPVCNodeFailureHandling) {
+	*out = *in
+	if in.GracePeriodSeconds != nil {
+		in, out := &in.GracePeriodSeconds, &out.GracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCNodeFailureHandling.
+func (in *FluentPVCNodeFailureHandling) DeepCopy() *FluentPVCNodeFailureHandling {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCNodeFailureHandling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCPodTemplateOverrides) DeepCopyInto(out *FluentPVCPodTemplateOverrides) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ContainerResources != nil {
+		in, out := &in.ContainerResources, &out.ContainerResources
+		*out = make(map[string]v1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCPodTemplateOverrides.
+func (in *FluentPVCPodTemplateOverrides) DeepCopy() *FluentPVCPodTemplateOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCPodTemplateOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCPodTemplateOverridesMetadata) DeepCopyInto(out *FluentPVCPodTemplateOverridesMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCPodTemplateOverridesMetadata.
+func (in *FluentPVCPodTemplateOverridesMetadata) DeepCopy() *FluentPVCPodTemplateOverridesMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCPodTemplateOverridesMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCRevision) DeepCopyInto(out *FluentPVCRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCRevision.
+func (in *FluentPVCRevision) DeepCopy() *FluentPVCRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FluentPVCRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCRevisionList) DeepCopyInto(out *FluentPVCRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]FluentPVCRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCRevisionList.
+func (in *FluentPVCRevisionList) DeepCopy() *FluentPVCRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *FluentPVCRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCSidecarUpdateStatus) DeepCopyInto(out *FluentPVCSidecarUpdateStatus) {
+	*out = *in
+	if in.RestartingWorkloads != nil {
+		in, out := &in.RestartingWorkloads, &out.RestartingWorkloads
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BlockedWorkloads != nil {
+		in, out := &in.BlockedWorkloads, &out.BlockedWorkloads
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCSidecarUpdateStatus.
+func (in *FluentPVCSidecarUpdateStatus) DeepCopy() *FluentPVCSidecarUpdateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCSidecarUpdateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCSidecarUpdateStrategy) DeepCopyInto(out *FluentPVCSidecarUpdateStrategy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCSidecarUpdateStrategy.
+func (in *FluentPVCSidecarUpdateStrategy) DeepCopy() *FluentPVCSidecarUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCSidecarUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCSnapshotBeforeDelete) DeepCopyInto(out *FluentPVCSnapshotBeforeDelete) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCSnapshotBeforeDelete.
+func (in *FluentPVCSnapshotBeforeDelete) DeepCopy() *FluentPVCSnapshotBeforeDelete {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCSnapshotBeforeDelete)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCSnapshotFinalization) DeepCopyInto(out *FluentPVCSnapshotFinalization) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCSnapshotFinalization.
+func (in *FluentPVCSnapshotFinalization) DeepCopy() *FluentPVCSnapshotFinalization {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCSnapshotFinalization)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FluentPVCSpec) DeepCopyInto(out *FluentPVCSpec) {
 	*out = *in
@@ -195,6 +489,48 @@ func (in *FluentPVCSpec) DeepCopyInto(out *FluentPVCSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DeletePodIfSidecarContainerTerminationDetected != nil {
+		in, out := &in.DeletePodIfSidecarContainerTerminationDetected, &out.DeletePodIfSidecarContainerTerminationDetected
+		*out = new(bool)
+		**out = **in
+	}
+	out.SidecarUpdateStrategy = in.SidecarUpdateStrategy
+	out.MergeStrategies = in.MergeStrategies
+	in.TargetContainers.DeepCopyInto(&out.TargetContainers)
+	if in.PVCVolumeMountSubPaths != nil {
+		in, out := &in.PVCVolumeMountSubPaths, &out.PVCVolumeMountSubPaths
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.PodTemplateOverrides.DeepCopyInto(&out.PodTemplateOverrides)
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxPVCsPerNamespace != nil {
+		in, out := &in.MaxPVCsPerNamespace, &out.MaxPVCsPerNamespace
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxTotalStoragePerNamespace != nil {
+		in, out := &in.MaxTotalStoragePerNamespace, &out.MaxTotalStoragePerNamespace
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	in.NodeFailureHandling.DeepCopyInto(&out.NodeFailureHandling)
+	if in.SnapshotBeforeDelete != nil {
+		in, out := &in.SnapshotBeforeDelete, &out.SnapshotBeforeDelete
+		*out = new(FluentPVCSnapshotBeforeDelete)
+		**out = **in
+	}
+	if in.SnapshotFinalization != nil {
+		in, out := &in.SnapshotFinalization, &out.SnapshotFinalization
+		*out = new(FluentPVCSnapshotFinalization)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCSpec.
@@ -217,6 +553,16 @@ func (in *FluentPVCStatus) DeepCopyInto(out *FluentPVCStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SidecarUpdate != nil {
+		in, out := &in.SidecarUpdate, &out.SidecarUpdate
+		*out = new(FluentPVCSidecarUpdateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DegradedPods != nil {
+		in, out := &in.DegradedPods, &out.DegradedPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCStatus.
@@ -229,6 +575,31 @@ func (in *FluentPVCStatus) DeepCopy() *FluentPVCStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FluentPVCTargetContainers) DeepCopyInto(out *FluentPVCTargetContainers) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeNames != nil {
+		in, out := &in.ExcludeNames, &out.ExcludeNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FluentPVCTargetContainers.
+func (in *FluentPVCTargetContainers) DeepCopy() *FluentPVCTargetContainers {
+	if in == nil {
+		return nil
+	}
+	out := new(FluentPVCTargetContainers)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectIdentity) DeepCopyInto(out *ObjectIdentity) {
 	*out = *in
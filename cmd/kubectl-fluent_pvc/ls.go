@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"golang.org/x/xerrors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+)
+
+func runList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	o := addCommonFlags(fs)
+	var phase string
+	var allNamespaces bool
+	fs.StringVar(&phase, "phase", "", "Phase of the FluentPVCBindings to list (e.g. FinalizerJobFailed).")
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "List the FluentPVCBindings in all namespaces.")
+	fs.BoolVar(&allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return xerrors.New("Usage: kubectl fluent-pvc ls [--phase <phase>] [-n <namespace>] [-A]")
+	}
+	c, namespace, err := o.newClient()
+	if err != nil {
+		return err
+	}
+
+	opts := []client.ListOption{}
+	if !allNamespaces {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+	if err := c.List(ctx, bindings, opts...); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPHASE\tFLUENTPVC\tPOD\tPVC\tAGE")
+	for i := range bindings.Items {
+		b := &bindings.Items[i]
+		if phase != "" && string(b.Status.Phase) != phase {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			b.Namespace, b.Name, b.Status.Phase, b.Spec.FluentPVC.Name, b.Spec.Pod.Name, b.Spec.PVC.Name, age(b.CreationTimestamp),
+		)
+	}
+	return w.Flush()
+}
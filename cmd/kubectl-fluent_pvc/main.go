@@ -0,0 +1,137 @@
+// kubectl-fluent_pvc is a kubectl plugin to inspect and operate the state of fluent-pvc-operator.
+// Put the binary into PATH, and then run it as 'kubectl fluent-pvc <command>'.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/xerrors"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+)
+
+const usage = `Inspect and operate the state of fluent-pvc-operator.
+
+Usage:
+  kubectl fluent-pvc status <pod> [-n <namespace>]
+      Show the FluentPVCBindings of the pod with the FluentPVC, the PVC, the finalizer Job and the conditions as a tree.
+  kubectl fluent-pvc ls [--phase <phase>] [-n <namespace>] [-A]
+      List FluentPVCBindings. Use '--phase FinalizerJobFailed' to list the bindings stuck in finalization.
+  kubectl fluent-pvc retry <binding> [-n <namespace>]
+      Re-run the failed finalizer Job of the FluentPVCBinding.
+  kubectl fluent-pvc force-release <binding> [-n <namespace>]
+      Remove the finalizers of the FluentPVCBinding and its PVC, and delete the FluentPVCBinding.
+      The PVC is kept. It is refused while the pod or the finalizer Job is running.
+
+Common flags:
+  --kubeconfig <path>  Path to the kubeconfig file.
+  -n, --namespace <ns> Namespace. Defaults to the namespace of the current context.
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(fluentpvcv1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return xerrors.New("A command is required.")
+	}
+	switch args[0] {
+	case "status":
+		return runStatus(ctx, args[1:])
+	case "ls":
+		return runList(ctx, args[1:])
+	case "retry":
+		return runRetry(ctx, args[1:])
+	case "force-release":
+		return runForceRelease(ctx, args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return nil
+	}
+	fmt.Fprint(os.Stderr, usage)
+	return xerrors.New(fmt.Sprintf("Unknown command='%s'.", args[0]))
+}
+
+type commonOptions struct {
+	kubeconfig string
+	namespace  string
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonOptions {
+	o := &commonOptions{}
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&o.namespace, "namespace", "", "Namespace. Defaults to the namespace of the current context.")
+	fs.StringVar(&o.namespace, "n", "", "Shorthand for --namespace.")
+	return o
+}
+
+// newClient returns a client and the namespace resolved in the same way as kubectl.
+func (o *commonOptions) newClient() (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = o.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{}
+	overrides.Context.Namespace = o.namespace
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	cfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", xerrors.Errorf("Cannot load the kubeconfig.: %w", err)
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, "", xerrors.Errorf("Cannot resolve the namespace.: %w", err)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return c, namespace, nil
+}
+
+// parseInterspersed parses the flags placed both before and after the positional arguments like kubectl,
+// and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// parseSingleArg parses the flags and returns the only positional argument.
+func parseSingleArg(fs *flag.FlagSet, args []string, name string) (string, error) {
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(positional) != 1 {
+		return "", xerrors.New(fmt.Sprintf("Usage: kubectl fluent-pvc %s <%s>", fs.Name(), name))
+	}
+	return positional[0], nil
+}
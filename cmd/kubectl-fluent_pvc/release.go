@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"golang.org/x/xerrors"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+)
+
+// runForceRelease releases the PVC from fluent-pvc-operator without finalization. The PVC is kept so that
+// the data can be recovered by hand.
+func runForceRelease(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("force-release", flag.ContinueOnError)
+	o := addCommonFlags(fs)
+	name, err := parseSingleArg(fs, args, "binding")
+	if err != nil {
+		return err
+	}
+	c, namespace, err := o.newClient()
+	if err != nil {
+		return err
+	}
+
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if err := checkReleasable(ctx, c, b); err != nil {
+		return err
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: b.Spec.PVC.Name}, pvc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if b.IsBindingPVC(pvc) && controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName) {
+		controllerutil.RemoveFinalizer(pvc, constants.PVCFinalizerName)
+		if err := c.Update(ctx, pvc); err != nil {
+			return xerrors.Errorf("Failed to remove finalizer from PVC='%s'.: %w", pvc.Name, err)
+		}
+		fmt.Printf("Removed the finalizer='%s' from PVC='%s'. The PVC is kept, so delete it by hand if the data is not needed.\n", constants.PVCFinalizerName, pvc.Name)
+	}
+
+	if controllerutil.ContainsFinalizer(b, constants.FluentPVCBindingFinalizerName) {
+		controllerutil.RemoveFinalizer(b, constants.FluentPVCBindingFinalizerName)
+		if err := c.Update(ctx, b); err != nil {
+			return xerrors.Errorf("Failed to remove finalizer from FluentPVCBinding='%s'.: %w", b.Name, err)
+		}
+		fmt.Printf("Removed the finalizer='%s' from FluentPVCBinding='%s'.\n", constants.FluentPVCBindingFinalizerName, b.Name)
+	}
+	if err := c.Delete(ctx, b, deleteOptionsBackground(b)); client.IgnoreNotFound(err) != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	fmt.Printf("Deleted FluentPVCBinding='%s'.\n", b.Name)
+	return nil
+}
+
+// checkReleasable returns an error if the PVC may be still written by the pod or the finalizer job.
+func checkReleasable(ctx context.Context, c client.Client, b *fluentpvcv1alpha1.FluentPVCBinding) error {
+	pod := &corev1.Pod{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: b.Spec.Pod.Name}, pod); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if b.IsBindingPod(pod) && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+		return xerrors.New(fmt.Sprintf("Pod='%s' is '%s' phase and may use PVC='%s', so it cannot be released.", pod.Name, pod.Status.Phase, b.Spec.PVC.Name))
+	}
+
+	j := &batchv1.Job{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: jobutils.FinalizerJobName(b)}, j); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if metav1.IsControlledBy(j, b) {
+		if finished, _ := jobutils.GetFinishedStatus(j); !finished {
+			return xerrors.New(fmt.Sprintf("The finalizer job='%s' is running, so it cannot be released.", j.Name))
+		}
+	}
+	return nil
+}
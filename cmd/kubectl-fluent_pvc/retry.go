@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"golang.org/x/xerrors"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+)
+
+// runRetry deletes the failed finalizer job. Then fluentPVCBindingReconciler clears the FinalizerJobApplied
+// and FinalizerJobFailed conditions, and pvcReconciler applies a new finalizer job.
+func runRetry(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("retry", flag.ContinueOnError)
+	o := addCommonFlags(fs)
+	name, err := parseSingleArg(fs, args, "binding")
+	if err != nil {
+		return err
+	}
+	c, namespace, err := o.newClient()
+	if err != nil {
+		return err
+	}
+
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if !b.IsConditionFinalizerJobFailed() {
+		return xerrors.New(fmt.Sprintf("FluentPVCBinding='%s' is not FinalizerJobFailed (phase='%s').", b.Name, b.Status.Phase))
+	}
+	if b.IsConditionUnknown() {
+		return xerrors.New(fmt.Sprintf("FluentPVCBinding='%s' is Unknown, so the controllers do not apply a new finalizer job. Annotate it with '%s=%s' instead.", b.Name, constants.FluentPVCBindingAnnotationAction, fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer))
+	}
+
+	j := &batchv1.Job{}
+	jobName := jobutils.FinalizerJobName(b)
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: jobName}, j); err != nil {
+		if apierrors.IsNotFound(err) {
+			fmt.Printf("The finalizer job='%s' is already deleted, so a new finalizer job will be applied.\n", jobName)
+			return nil
+		}
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if !metav1.IsControlledBy(j, b) {
+		return xerrors.New(fmt.Sprintf("Job='%s' is not controlled by FluentPVCBinding='%s'.", j.Name, b.Name))
+	}
+	if err := c.Delete(ctx, j, deleteOptionsBackground(j)); client.IgnoreNotFound(err) != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	fmt.Printf("Deleted the finalizer job='%s', so a new finalizer job will be applied.\n", j.Name)
+	return nil
+}
+
+func deleteOptionsBackground(obj client.Object) *client.DeleteOptions {
+	uid := obj.GetUID()
+	resourceVersion := obj.GetResourceVersion()
+	policy := metav1.DeletePropagationBackground
+	return &client.DeleteOptions{
+		Preconditions: &metav1.Preconditions{
+			UID:             &uid,
+			ResourceVersion: &resourceVersion,
+		},
+		PropagationPolicy: &policy,
+	}
+}
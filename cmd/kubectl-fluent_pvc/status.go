@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+)
+
+func runStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	o := addCommonFlags(fs)
+	podName, err := parseSingleArg(fs, args, "pod")
+	if err != nil {
+		return err
+	}
+	c, namespace, err := o.newClient()
+	if err != nil {
+		return err
+	}
+
+	bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+	if err := c.List(ctx, bindings, client.InNamespace(namespace)); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	pod := &corev1.Pod{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: podName}, pod); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		pod = nil
+	}
+
+	root := &treeNode{text: fmt.Sprintf("Pod %s/%s (%s)", namespace, podName, describePod(pod))}
+	for i := range bindings.Items {
+		b := &bindings.Items[i]
+		// NOTE: A pod of the same name may have multiple bindings if the PVC is reattached or the pod is recreated.
+		if b.Spec.Pod.Name != podName {
+			continue
+		}
+		n, err := describeFluentPVCBinding(ctx, c, b)
+		if err != nil {
+			return err
+		}
+		root.children = append(root.children, n)
+	}
+	if len(root.children) == 0 {
+		return xerrors.New(fmt.Sprintf("FluentPVCBinding for pod='%s'(namespace='%s') is not found.", podName, namespace))
+	}
+	root.print(os.Stdout, "", "")
+	return nil
+}
+
+func describeFluentPVCBinding(ctx context.Context, c client.Client, b *fluentpvcv1alpha1.FluentPVCBinding) (*treeNode, error) {
+	n := &treeNode{text: fmt.Sprintf("FluentPVCBinding %s (phase: %s, age: %s)", b.Name, b.Status.Phase, age(b.CreationTimestamp))}
+
+	revision := "none"
+	if b.Spec.FluentPVCRevision != nil {
+		revision = b.Spec.FluentPVCRevision.Name
+	}
+	n.children = append(n.children, &treeNode{text: fmt.Sprintf("FluentPVC %s (revision: %s)", b.Spec.FluentPVC.Name, revision)})
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: b.Spec.PVC.Name}, pvc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		n.children = append(n.children, &treeNode{text: fmt.Sprintf("PVC %s (not found)", b.Spec.PVC.Name)})
+	} else {
+		n.children = append(n.children, &treeNode{text: fmt.Sprintf("PVC %s (%s)", pvc.Name, describePVC(b, pvc))})
+	}
+
+	if b.Status.FinalizerPVC != nil {
+		finalizerPVC := &corev1.PersistentVolumeClaim{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: b.Status.FinalizerPVC.Name}, finalizerPVC); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			n.children = append(n.children, &treeNode{text: fmt.Sprintf("Finalizer PVC %s (not found)", b.Status.FinalizerPVC.Name)})
+		} else {
+			n.children = append(n.children, &treeNode{text: fmt.Sprintf(
+				"Finalizer PVC %s (phase: %s, finalizers: [%s])",
+				finalizerPVC.Name, finalizerPVC.Status.Phase, strings.Join(finalizerPVC.Finalizers, ","),
+			)})
+		}
+	}
+
+	j := &batchv1.Job{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: jobutils.FinalizerJobName(b)}, j); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		n.children = append(n.children, &treeNode{text: fmt.Sprintf("Job %s (not applied)", jobutils.FinalizerJobName(b))})
+	} else if metav1.IsControlledBy(j, b) {
+		n.children = append(n.children, &treeNode{text: fmt.Sprintf("Job %s (%s)", j.Name, describeJob(j))})
+	}
+
+	if r := b.Status.FinalizerJobResult; r != nil {
+		n.children = append(n.children, &treeNode{text: fmt.Sprintf("Result %s", describeFinalizerJobResult(r))})
+	}
+
+	conditions := &treeNode{text: "Conditions"}
+	for _, cond := range b.Status.Conditions {
+		conditions.children = append(conditions.children, &treeNode{text: fmt.Sprintf(
+			"%s=%s (reason: %s, since: %s) %s",
+			cond.Type, cond.Status, cond.Reason, age(cond.LastTransitionTime), cond.Message,
+		)})
+	}
+	n.children = append(n.children, conditions)
+	return n, nil
+}
+
+func describePod(pod *corev1.Pod) string {
+	if pod == nil {
+		return "not found"
+	}
+	return fmt.Sprintf("phase: %s, uid: %s", pod.Status.Phase, pod.UID)
+}
+
+func describePVC(b *fluentpvcv1alpha1.FluentPVCBinding, pvc *corev1.PersistentVolumeClaim) string {
+	s := fmt.Sprintf("phase: %s, finalizers: [%s]", pvc.Status.Phase, strings.Join(pvc.Finalizers, ","))
+	if !b.IsBindingPVC(pvc) {
+		s += fmt.Sprintf(", uid: %s is not bound by the FluentPVCBinding", pvc.UID)
+	}
+	if !pvc.DeletionTimestamp.IsZero() {
+		s += ", terminating"
+	}
+	return s
+}
+
+func describeJob(j *batchv1.Job) string {
+	status := "Running"
+	switch {
+	case jobutils.IsSucceeded(j):
+		status = "Succeeded"
+	case jobutils.IsFailed(j):
+		status = "Failed"
+	}
+	return fmt.Sprintf("status: %s, active: %d, succeeded: %d, failed: %d", status, j.Status.Active, j.Status.Succeeded, j.Status.Failed)
+}
+
+func describeFinalizerJobResult(r *fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult) string {
+	s := fmt.Sprintf("destination: %s, checksum: %s", r.DestinationURI, r.Checksum)
+	if r.BytesShipped != nil {
+		s += fmt.Sprintf(", bytes: %d", *r.BytesShipped)
+	}
+	if r.Records != nil {
+		s += fmt.Sprintf(", records: %d", *r.Records)
+	}
+	return s + fmt.Sprintf(", reported by: %s/%s", r.PodName, r.ContainerName)
+}
+
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+type treeNode struct {
+	text     string
+	children []*treeNode
+}
+
+func (n *treeNode) print(w io.Writer, prefix, childPrefix string) {
+	fmt.Fprintf(w, "%s%s\n", prefix, n.text)
+	for i, c := range n.children {
+		if i == len(n.children)-1 {
+			c.print(w, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			c.print(w, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
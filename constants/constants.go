@@ -1,10 +1,25 @@
 package constants
 
 const (
-	OwnerControllerField          = ".metadata.ownerReference.controller"
-	PodLabelFluentPVCName         = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-name"
-	PodLabelFluentPVCBindingName  = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-binding-name"
-	PVCFinalizerName              = "fluent-pvc-operator.tech.zozo.com/pvc-protection"
-	FluentPVCBindingFinalizerName = "fluent-pvc-operator.tech.zozo.com/fluentpvcbinding-protection"
-	FluentPVCFinalizerName        = "fluent-pvc-operator.tech.zozo.com/fluentpvc-protection"
+	OwnerControllerField                        = ".metadata.ownerReference.controller"
+	PodNodeNameField                            = ".spec.nodeName"
+	PodFluentPVCNameField                       = ".metadata.labels.fluentPVCName"
+	FluentPVCBindingFluentPVCNameField          = ".spec.fluentPVC.name"
+	PodLabelFluentPVCName                       = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-name"
+	PodLabelFluentPVCBindingName                = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-binding-name"
+	PodLabelAdmissionFailurePolicy              = "fluent-pvc-operator.tech.zozo.com/admission-failure-policy"
+	PodLabelDegraded                            = "fluent-pvc-operator.tech.zozo.com/degraded"
+	PVCAnnotationFluentPVCBindingName           = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-binding-name"
+	PVCFinalizerName                            = "fluent-pvc-operator.tech.zozo.com/pvc-protection"
+	FluentPVCBindingFinalizerName               = "fluent-pvc-operator.tech.zozo.com/fluentpvcbinding-protection"
+	FluentPVCFinalizerName                      = "fluent-pvc-operator.tech.zozo.com/fluentpvc-protection"
+	PodTemplateAnnotationRestartedAt            = "fluent-pvc-operator.tech.zozo.com/restarted-at"
+	PodTemplateAnnotationRestartedRevision      = "fluent-pvc-operator.tech.zozo.com/restarted-revision"
+	FluentPVCBindingAnnotationAction            = "fluent-pvc-operator.tech.zozo.com/action"
+	FluentPVCBindingAnnotationActionRequestedBy = "fluent-pvc-operator.tech.zozo.com/action-requested-by"
+	VolumeSnapshotLabelFluentPVCName            = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-name"
+	VolumeSnapshotLabelFluentPVCBindingName     = "fluent-pvc-operator.tech.zozo.com/fluent-pvc-binding-name"
+	VolumeSnapshotAnnotationRetainUntil         = "fluent-pvc-operator.tech.zozo.com/retain-until"
+	StorageClassAnnotationIsDefaultClass        = "storageclass.kubernetes.io/is-default-class"
+	StorageClassAnnotationBetaIsDefaultClass    = "storageclass.beta.kubernetes.io/is-default-class"
 )
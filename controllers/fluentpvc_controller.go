@@ -3,24 +3,42 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	"golang.org/x/xerrors"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
 	"github.com/st-tech/fluent-pvc-operator/constants"
+	revisionutils "github.com/st-tech/fluent-pvc-operator/utils/revision"
 )
 
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcrevisions,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="apps",resources=replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="apps",resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs=get;list;watch
 
 type fluentPVCReconciler struct {
 	client.Client
@@ -70,9 +88,304 @@ func (r *fluentPVCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err := r.Update(ctx, fpvc); err != nil {
 		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
+	if !fpvc.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	rev, err := revisionutils.GetOrCreateFluentPVCRevision(ctx, r.Client, r.Scheme, fpvc)
+	if err != nil {
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if fpvc.Status.CurrentRevision != rev.Name {
+		logger.Info(fmt.Sprintf("Update the current revision of fluentpvc='%s' to fluentpvcrevision='%s'.", fpvc.Name, rev.Name))
+		fpvc.Status.CurrentRevision = rev.Name
+		if err := r.Status().Update(ctx, fpvc); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	}
+	if err := r.updateDegradedPods(ctx, fpvc); err != nil {
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if err := r.pruneFluentPVCRevisions(ctx, fpvc, bindings); err != nil {
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return r.updateSidecarContainers(ctx, fpvc, rev, bindings)
+}
+
+// updateDegradedPods records the pods admitted without the PVC of the fluentpvc in the status.
+// See FluentPVCAdmissionFailurePolicyIgnore.
+func (r *fluentPVCReconciler) updateDegradedPods(ctx context.Context, fpvc *fluentpvcv1alpha1.FluentPVC) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCReconciler").WithName("updateDegradedPods")
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.MatchingFields{constants.PodFluentPVCNameField: fpvc.Name}); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	degraded := []string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if isDegradedPod(pod, fpvc) {
+			degraded = append(degraded, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		}
+	}
+	sort.Strings(degraded)
+	if len(degraded) == 0 {
+		degraded = nil
+	}
+	if apiequality.Semantic.DeepEqual(fpvc.Status.DegradedPods, degraded) {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Update the degraded pods of fluentpvc='%s' to %v.", fpvc.Name, degraded))
+	fpvc.Status.DegradedPods = degraded
+	if err := r.Status().Update(ctx, fpvc); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return nil
+}
+
+// pruneFluentPVCRevisions deletes the fluentpvcrevisions that are neither current nor used by any fluentpvcbindings.
+func (r *fluentPVCReconciler) pruneFluentPVCRevisions(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	bindings *fluentpvcv1alpha1.FluentPVCBindingList,
+) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCReconciler").WithName("pruneFluentPVCRevisions")
+	revisions := &fluentpvcv1alpha1.FluentPVCRevisionList{}
+	if err := r.List(ctx, revisions, matchingOwnerControllerField(fpvc.Name)); client.IgnoreNotFound(err) != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	used := map[types.UID]bool{}
+	for _, b := range bindings.Items {
+		if b.Spec.FluentPVCRevision != nil {
+			used[b.Spec.FluentPVCRevision.UID] = true
+		}
+	}
+	for i := range revisions.Items {
+		rev := &revisions.Items[i]
+		if !revisionutils.IsFluentPVCRevisionOf(rev, fpvc) || rev.Name == fpvc.Status.CurrentRevision || used[rev.UID] {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Delete fluentpvcrevision='%s' because it is not used by any fluentpvcbindings.", rev.Name))
+		if err := r.Delete(ctx, rev, deleteOptionsBackground(&rev.UID, &rev.ResourceVersion)); client.IgnoreNotFound(err) != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	}
+	return nil
+}
+
+// updateSidecarContainers restarts the workloads of the pods whose sidecar containers are injected by
+// a stale revision if the sidecar update strategy is RollingRestart, and reports the progress.
+func (r *fluentPVCReconciler) updateSidecarContainers(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	current *fluentpvcv1alpha1.FluentPVCRevision,
+	bindings *fluentpvcv1alpha1.FluentPVCBindingList,
+) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCReconciler").WithName("updateSidecarContainers")
+	if fpvc.Spec.SidecarUpdateStrategy.Type != fluentpvcv1alpha1.FluentPVCSidecarUpdateStrategyRollingRestart {
+		if fpvc.Status.SidecarUpdate != nil {
+			fpvc.Status.SidecarUpdate = nil
+			if err := r.Status().Update(ctx, fpvc); err != nil {
+				return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+	maxConcurrentRestarts := int(fpvc.Spec.SidecarUpdateStrategy.MaxConcurrentRestarts)
+	if maxConcurrentRestarts < 1 {
+		maxConcurrentRestarts = 1
+	}
+
+	status := &fluentpvcv1alpha1.FluentPVCSidecarUpdateStatus{Revision: current.Name}
+	workloads := map[string]client.Object{}
+	disruptionBlocked := map[string]bool{}
+	blocked := map[string]bool{}
+	for i := range bindings.Items {
+		b := &bindings.Items[i]
+		if !b.DeletionTimestamp.IsZero() || b.Spec.Pod.Name == "" ||
+			b.IsConditionOutOfUse() || b.IsConditionUnknown() || b.IsConditionPodMissing() {
+			continue
+		}
+		stale, err := r.isSidecarContainerStale(ctx, b, current)
+		if err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if !stale {
+			status.UpdatedPods++
+			continue
+		}
+		status.StalePods++
+		pod := &corev1.Pod{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: b.Spec.Pod.Name}, pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		w, err := r.findOwnerWorkload(ctx, pod)
+		if err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if w == nil {
+			blocked[workloadKey("Pod", pod)] = true
+			continue
+		}
+		key := workloadKey(w.GetObjectKind().GroupVersionKind().Kind, w)
+		workloads[key] = w
+		allowed, err := r.isDisruptionAllowed(ctx, pod)
+		if err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if !allowed {
+			disruptionBlocked[key] = true
+		}
+	}
+
+	keys := []string{}
+	for k := range workloads {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	restartable := []string{}
+	for _, k := range keys {
+		w := workloads[k]
+		if podTemplateOf(w).Annotations[constants.PodTemplateAnnotationRestartedRevision] == current.Name {
+			if isWorkloadRolledOut(w) {
+				// NOTE: The workload is already restarted, but the pods are not updated by its update strategy.
+				blocked[k] = true
+			} else {
+				status.RestartingWorkloads = append(status.RestartingWorkloads, k)
+			}
+			continue
+		}
+		if disruptionBlocked[k] {
+			blocked[k] = true
+			continue
+		}
+		// NOTE: The workloads are restarted by their update strategies, so maxConcurrentRestarts does not
+		// bypass maxUnavailable of each workload.
+		ok, err := hasWorkloadRolloutCapacity(w)
+		if err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if !ok {
+			blocked[k] = true
+			continue
+		}
+		restartable = append(restartable, k)
+	}
+	for _, k := range restartable {
+		if len(status.RestartingWorkloads) >= maxConcurrentRestarts {
+			break
+		}
+		w := workloads[k]
+		logger.Info(fmt.Sprintf(
+			"Restart workload='%s' because the sidecar containers are stale. (fluentpvc='%s', fluentpvcrevision='%s')",
+			k, fpvc.Name, current.Name,
+		))
+		patch := client.MergeFrom(w.DeepCopyObject().(client.Object))
+		template := podTemplateOf(w)
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[constants.PodTemplateAnnotationRestartedAt] = time.Now().Format(time.RFC3339)
+		template.Annotations[constants.PodTemplateAnnotationRestartedRevision] = current.Name
+		if err := r.Patch(ctx, w, patch); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		status.RestartingWorkloads = append(status.RestartingWorkloads, k)
+	}
+	for k := range blocked {
+		status.BlockedWorkloads = append(status.BlockedWorkloads, k)
+	}
+	sort.Strings(status.BlockedWorkloads)
+
+	if !apiequality.Semantic.DeepEqual(fpvc.Status.SidecarUpdate, status) {
+		fpvc.Status.SidecarUpdate = status
+		if err := r.Status().Update(ctx, fpvc); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	}
+	if status.StalePods != 0 {
+		return requeueResult(30 * time.Second), nil
+	}
 	return ctrl.Result{}, nil
 }
 
+func (r *fluentPVCReconciler) isSidecarContainerStale(
+	ctx context.Context,
+	b *fluentpvcv1alpha1.FluentPVCBinding,
+	current *fluentpvcv1alpha1.FluentPVCRevision,
+) (bool, error) {
+	if b.Spec.FluentPVCRevision == nil {
+		// NOTE: The pod is injected before revisions are recorded, so the sidecar container is unknown.
+		return true, nil
+	}
+	if b.Spec.FluentPVCRevision.UID == current.UID {
+		return false, nil
+	}
+	rev := &fluentpvcv1alpha1.FluentPVCRevision{}
+	if err := r.Get(ctx, client.ObjectKey{Name: b.Spec.FluentPVCRevision.Name}, rev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return !revisionutils.EqualPodInjection(&rev.Spec, &current.Spec), nil
+}
+
+// findOwnerWorkload returns the Deployment, StatefulSet or DaemonSet that owns the pod.
+// nil is returned if the pod is not owned by any of them.
+func (r *fluentPVCReconciler) findOwnerWorkload(ctx context.Context, pod *corev1.Pod) (client.Object, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil, nil
+	}
+	var w client.Object
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs := &appsv1.ReplicaSet{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: owner.Name}, rs); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		owner = metav1.GetControllerOf(rs)
+		if owner == nil || owner.APIVersion != appsv1.SchemeGroupVersion.String() || owner.Kind != "Deployment" {
+			return nil, nil
+		}
+		w = &appsv1.Deployment{}
+	case "StatefulSet":
+		w = &appsv1.StatefulSet{}
+	case "DaemonSet":
+		w = &appsv1.DaemonSet{}
+	default:
+		return nil, nil
+	}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: owner.Name}, w); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	w.GetObjectKind().SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind(owner.Kind))
+	return w, nil
+}
+
+func (r *fluentPVCReconciler) isDisruptionAllowed(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	pdbs := &policyv1.PodDisruptionBudgetList{}
+	if err := r.List(ctx, pdbs, client.InNamespace(pod.Namespace)); err != nil {
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if pdb.Status.DisruptionsAllowed < 1 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func (r *fluentPVCReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	ctx := context.Background()
 	if err := mgr.GetFieldIndexer().IndexField(
@@ -83,6 +396,22 @@ func (r *fluentPVCReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	); err != nil {
 		return xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		ctx,
+		&fluentpvcv1alpha1.FluentPVCRevision{},
+		constants.OwnerControllerField,
+		indexFluentPVCRevisionByOwnerFluentPVC,
+	); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		ctx,
+		&corev1.Pod{},
+		constants.PodFluentPVCNameField,
+		indexPodByFluentPVCName,
+	); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
 	pred := predicate.Funcs{
 		CreateFunc:  func(event.CreateEvent) bool { return true },
 		DeleteFunc:  func(event.DeleteEvent) bool { return false },
@@ -93,5 +422,39 @@ func (r *fluentPVCReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&fluentpvcv1alpha1.FluentPVC{}).
 		Owns(&fluentpvcv1alpha1.FluentPVCBinding{}).
 		WithEventFilter(pred).
+		// NOTE: The degraded pods which are deleted are removed from the status on the next reconciliation.
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(r.mapDegradedPodToFluentPVC)).
 		Complete(r)
 }
+
+func (r *fluentPVCReconciler) mapDegradedPodToFluentPVC(obj client.Object) []reconcile.Request {
+	logger := ctrl.Log.WithName("fluentPVCReconciler").WithName("mapDegradedPodToFluentPVC")
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	name, ok := pod.Labels[constants.PodLabelFluentPVCName]
+	if !ok {
+		return nil
+	}
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := r.Get(context.Background(), client.ObjectKey{Name: name}, fpvc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("Cannot get fluentpvc='%s' of pod='%s'.", name, pod.Name))
+		}
+		return nil
+	}
+	if !isDegradedPod(pod, fpvc) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// isDegradedPod returns true if the pod is admitted without the PVC of the fluentpvc.
+func isDegradedPod(pod *corev1.Pod, fpvc *fluentpvcv1alpha1.FluentPVC) bool {
+	if _, ok := pod.Labels[constants.PodLabelDegraded]; ok {
+		return true
+	}
+	// NOTE: The pods admitted while the pod webhook is unavailable have neither the label nor the sidecar container.
+	return !hasContainer(pod, fpvc.Spec.SidecarContainerTemplate.Name)
+}
@@ -0,0 +1,505 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+)
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := fluentpvcv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func newTestClient(t *testing.T, objects ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objects...).Build()
+}
+
+func TestUpdateSidecarContainers(t *testing.T) {
+	const namespace = "default"
+	oldRevision := &fluentpvcv1alpha1.FluentPVCRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc-old", UID: "old-revision-uid"},
+		Spec: fluentpvcv1alpha1.FluentPVCSpec{
+			SidecarContainerTemplate: corev1.Container{Name: "sidecar", Image: "fluentd:v1"},
+		},
+		Revision: 1,
+	}
+	currentRevision := &fluentpvcv1alpha1.FluentPVCRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc-current", UID: "current-revision-uid"},
+		Spec: fluentpvcv1alpha1.FluentPVCSpec{
+			SidecarContainerTemplate: corev1.Container{Name: "sidecar", Image: "fluentd:v2"},
+		},
+		Revision: 2,
+	}
+	// newDeployment returns the Deployment with 4 replicas, its ReplicaSet, its pod and the FluentPVCBinding of
+	// the pod injected with the revision.
+	newDeployment := func(name string, revision *fluentpvcv1alpha1.FluentPVCRevision) (*appsv1.Deployment, []client.Object) {
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID(name + "-uid")},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: pointer.Int32Ptr(4),
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				},
+			},
+			Status: appsv1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 4, AvailableReplicas: 4},
+		}
+		rs := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name + "-rs",
+				Namespace:       namespace,
+				UID:             types.UID(name + "-rs-uid"),
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(d, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name + "-pod",
+				Namespace:       namespace,
+				Labels:          map[string]string{"app": name},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rs, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))},
+			},
+		}
+		b := &fluentpvcv1alpha1.FluentPVCBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-binding", Namespace: namespace},
+			Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+				Pod:               fluentpvcv1alpha1.ObjectIdentity{Name: pod.Name},
+				FluentPVCRevision: &fluentpvcv1alpha1.ObjectIdentity{Name: revision.Name, UID: revision.UID},
+			},
+		}
+		return d, []client.Object{rs, pod, b}
+	}
+	newPDB := func(name string, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+	cases := []struct {
+		name                  string
+		maxConcurrentRestarts int32
+		// deployments modifies the Deployments named 'app-0', 'app-1', ... before they are created.
+		deployments     []func(d *appsv1.Deployment)
+		revisions       []*fluentpvcv1alpha1.FluentPVCRevision
+		objects         []client.Object
+		wantUpdatedPods int32
+		wantStalePods   int32
+		wantRestarting  []string
+		wantBlocked     []string
+		wantRestarted   []string
+	}{
+		{
+			name:           "restart the workload of the stale pod",
+			deployments:    []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {}},
+			revisions:      []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			wantStalePods:  1,
+			wantRestarting: []string{"Deployment/default/app-0"},
+			wantRestarted:  []string{"app-0"},
+		},
+		{
+			name:            "do not restart the workload of the updated pod",
+			deployments:     []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {}},
+			revisions:       []*fluentpvcv1alpha1.FluentPVCRevision{currentRevision},
+			wantUpdatedPods: 1,
+		},
+		{
+			name:          "block the workload whose PodDisruptionBudget does not allow disruptions",
+			deployments:   []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {}},
+			revisions:     []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			objects:       []client.Object{newPDB("app-0", 0)},
+			wantStalePods: 1,
+			wantBlocked:   []string{"Deployment/default/app-0"},
+		},
+		{
+			name:           "restart the workload whose PodDisruptionBudget allows disruptions",
+			deployments:    []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {}},
+			revisions:      []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			objects:        []client.Object{newPDB("app-0", 1)},
+			wantStalePods:  1,
+			wantRestarting: []string{"Deployment/default/app-0"},
+			wantRestarted:  []string{"app-0"},
+		},
+		{
+			name: "block the workload whose unavailable pods reach maxUnavailable",
+			deployments: []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {
+				maxUnavailable := intstr.FromInt(1)
+				d.Spec.Strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable}
+				d.Status.UnavailableReplicas = 1
+			}},
+			revisions:     []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			wantStalePods: 1,
+			wantBlocked:   []string{"Deployment/default/app-0"},
+		},
+		{
+			name: "restart the workload whose unavailable pods are less than maxUnavailable",
+			deployments: []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {
+				maxUnavailable := intstr.FromString("50%")
+				d.Spec.Strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable}
+				d.Status.UnavailableReplicas = 1
+			}},
+			revisions:      []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			wantStalePods:  1,
+			wantRestarting: []string{"Deployment/default/app-0"},
+			wantRestarted:  []string{"app-0"},
+		},
+		{
+			name:                  "restart the workloads up to maxConcurrentRestarts",
+			maxConcurrentRestarts: 1,
+			deployments:           []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {}, func(d *appsv1.Deployment) {}},
+			revisions:             []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision, oldRevision},
+			wantStalePods:         2,
+			wantRestarting:        []string{"Deployment/default/app-0"},
+			wantRestarted:         []string{"app-0"},
+		},
+		{
+			name: "keep the restarting workload without restarting it again",
+			deployments: []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {
+				d.Spec.Template.Annotations = map[string]string{constants.PodTemplateAnnotationRestartedRevision: currentRevision.Name}
+				d.Status.UpdatedReplicas = 2
+			}},
+			revisions:      []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			wantStalePods:  1,
+			wantRestarting: []string{"Deployment/default/app-0"},
+		},
+		{
+			name: "block the workload rolled out without updating the pods",
+			deployments: []func(d *appsv1.Deployment){func(d *appsv1.Deployment) {
+				d.Spec.Template.Annotations = map[string]string{constants.PodTemplateAnnotationRestartedRevision: currentRevision.Name}
+			}},
+			revisions:     []*fluentpvcv1alpha1.FluentPVCRevision{oldRevision},
+			wantStalePods: 1,
+			wantBlocked:   []string{"Deployment/default/app-0"},
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			fpvc := &fluentpvcv1alpha1.FluentPVC{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc"},
+				Spec: fluentpvcv1alpha1.FluentPVCSpec{
+					SidecarContainerTemplate: currentRevision.Spec.SidecarContainerTemplate,
+					SidecarUpdateStrategy: fluentpvcv1alpha1.FluentPVCSidecarUpdateStrategy{
+						Type:                  fluentpvcv1alpha1.FluentPVCSidecarUpdateStrategyRollingRestart,
+						MaxConcurrentRestarts: c.maxConcurrentRestarts,
+					},
+				},
+			}
+			objects := []client.Object{fpvc, oldRevision.DeepCopy(), currentRevision.DeepCopy()}
+			objects = append(objects, c.objects...)
+			bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+			for i, modify := range c.deployments {
+				d, owned := newDeployment(fmt.Sprintf("app-%d", i), c.revisions[i])
+				modify(d)
+				objects = append(objects, d)
+				objects = append(objects, owned...)
+				bindings.Items = append(bindings.Items, *owned[2].(*fluentpvcv1alpha1.FluentPVCBinding))
+			}
+			r := &fluentPVCReconciler{Client: newTestClient(t, objects...), Scheme: newTestScheme(t)}
+
+			ctx := context.Background()
+			if _, err := r.updateSidecarContainers(ctx, fpvc, currentRevision, bindings); err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+
+			status := fpvc.Status.SidecarUpdate
+			if status == nil {
+				t.Fatal("expected the sidecar update status, but got nil")
+			}
+			if status.Revision != currentRevision.Name {
+				t.Errorf("expected revision='%s', but got '%s'", currentRevision.Name, status.Revision)
+			}
+			if status.UpdatedPods != c.wantUpdatedPods || status.StalePods != c.wantStalePods {
+				t.Errorf("expected updatedPods=%d and stalePods=%d, but got %d and %d",
+					c.wantUpdatedPods, c.wantStalePods, status.UpdatedPods, status.StalePods)
+			}
+			if !reflect.DeepEqual(status.RestartingWorkloads, c.wantRestarting) {
+				t.Errorf("expected restartingWorkloads=%v, but got %v", c.wantRestarting, status.RestartingWorkloads)
+			}
+			if !reflect.DeepEqual(status.BlockedWorkloads, c.wantBlocked) {
+				t.Errorf("expected blockedWorkloads=%v, but got %v", c.wantBlocked, status.BlockedWorkloads)
+			}
+			var restarted []string
+			for i := range c.deployments {
+				d := &appsv1.Deployment{}
+				if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: fmt.Sprintf("app-%d", i)}, d); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := d.Spec.Template.Annotations[constants.PodTemplateAnnotationRestartedAt]; ok {
+					restarted = append(restarted, d.Name)
+				}
+			}
+			if !reflect.DeepEqual(restarted, c.wantRestarted) {
+				t.Errorf("expected the restarted deployments=%v, but got %v", c.wantRestarted, restarted)
+			}
+		})
+	}
+}
+
+func TestHasWorkloadRolloutCapacity(t *testing.T) {
+	maxUnavailable := func(v intstr.IntOrString) *intstr.IntOrString { return &v }
+	cases := []struct {
+		name     string
+		workload client.Object
+		want     bool
+	}{
+		{
+			name: "Deployment with the Recreate strategy",
+			workload: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}},
+				Status: appsv1.DeploymentStatus{UnavailableReplicas: 4},
+			},
+			want: true,
+		},
+		{
+			name: "Deployment without unavailable pods",
+			workload: &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{
+					Replicas: pointer.Int32Ptr(4),
+					Strategy: appsv1.DeploymentStrategy{RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: maxUnavailable(intstr.FromInt(0))}},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Deployment with unavailable pods under the default maxUnavailable",
+			workload: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(8)},
+				Status: appsv1.DeploymentStatus{UnavailableReplicas: 1},
+			},
+			want: true,
+		},
+		{
+			name: "Deployment with unavailable pods reaching the default maxUnavailable",
+			workload: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: pointer.Int32Ptr(8)},
+				Status: appsv1.DeploymentStatus{UnavailableReplicas: 2},
+			},
+			want: false,
+		},
+		{
+			name: "StatefulSet with the OnDelete strategy",
+			workload: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}},
+			},
+			want: false,
+		},
+		{
+			name: "StatefulSet partitioned over all the replicas",
+			workload: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: pointer.Int32Ptr(3),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: pointer.Int32Ptr(3)},
+					},
+				},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 3},
+			},
+			want: false,
+		},
+		{
+			name: "StatefulSet with all the replicas ready",
+			workload: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: pointer.Int32Ptr(3)},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 3},
+			},
+			want: true,
+		},
+		{
+			name: "StatefulSet with a replica not ready",
+			workload: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: pointer.Int32Ptr(3)},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 2},
+			},
+			want: false,
+		},
+		{
+			name: "DaemonSet with the OnDelete strategy",
+			workload: &appsv1.DaemonSet{
+				Spec: appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}},
+			},
+			want: false,
+		},
+		{
+			name: "DaemonSet with unavailable pods reaching the default maxUnavailable",
+			workload: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 10, NumberUnavailable: 1},
+			},
+			want: false,
+		},
+		{
+			name: "DaemonSet with unavailable pods under maxUnavailable rounded up",
+			workload: &appsv1.DaemonSet{
+				Spec: appsv1.DaemonSetSpec{
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+						RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: maxUnavailable(intstr.FromString("15%"))},
+					},
+				},
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 10, NumberUnavailable: 1},
+			},
+			want: true,
+		},
+		{
+			name:     "unsupported workload",
+			workload: &appsv1.ReplicaSet{},
+			want:     false,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got, err := hasWorkloadRolloutCapacity(c.workload)
+			if err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+			if got != c.want {
+				t.Errorf("expected %t, but got %t", c.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateDegradedPods(t *testing.T) {
+	const namespace = "default"
+	fpvc := &fluentpvcv1alpha1.FluentPVC{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc"},
+		Spec: fluentpvcv1alpha1.FluentPVCSpec{
+			SidecarContainerTemplate: corev1.Container{Name: "sidecar", Image: "fluentd:v1"},
+		},
+		Status: fluentpvcv1alpha1.FluentPVCStatus{DegradedPods: []string{"default/deleted"}},
+	}
+	newPod := func(name, degraded string, phase corev1.PodPhase, containers ...string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{constants.PodLabelFluentPVCName: fpvc.Name},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		if degraded != "" {
+			pod.Labels[constants.PodLabelDegraded] = degraded
+		}
+		for _, c := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c, Image: "busybox"})
+		}
+		return pod
+	}
+	objects := []client.Object{
+		fpvc.DeepCopy(),
+		newPod("injected", "", corev1.PodRunning, "app", "sidecar"),
+		newPod("empty-dir", "EmptyDir", corev1.PodRunning, "app", "sidecar"),
+		newPod("unmodified", "Unmodified", corev1.PodRunning, "app"),
+		newPod("without-webhook", "", corev1.PodPending, "app"),
+		newPod("succeeded", "", corev1.PodSucceeded, "app"),
+	}
+	r := &fluentPVCReconciler{Client: newTestClient(t, objects...), Scheme: newTestScheme(t)}
+	ctx := context.Background()
+	got := &fluentpvcv1alpha1.FluentPVC{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(fpvc), got); err != nil {
+		t.Fatalf("expected no error, but got %+v", err)
+	}
+	if err := r.updateDegradedPods(ctx, got); err != nil {
+		t.Fatalf("expected no error, but got %+v", err)
+	}
+	want := []string{"default/empty-dir", "default/unmodified", "default/without-webhook"}
+	if !reflect.DeepEqual(got.Status.DegradedPods, want) {
+		t.Errorf("expected %v, but got %v", want, got.Status.DegradedPods)
+	}
+}
+
+func TestMapDegradedPodToFluentPVC(t *testing.T) {
+	const namespace = "default"
+	fpvc := &fluentpvcv1alpha1.FluentPVC{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc"},
+		Spec: fluentpvcv1alpha1.FluentPVCSpec{
+			SidecarContainerTemplate: corev1.Container{Name: "sidecar", Image: "fluentd:v1"},
+		},
+	}
+	newPod := func(labels map[string]string, containers ...string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: namespace, Labels: labels}}
+		for _, c := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c, Image: "busybox"})
+		}
+		return pod
+	}
+	cases := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{
+			name: "injected pod",
+			pod:  newPod(map[string]string{constants.PodLabelFluentPVCName: fpvc.Name}, "app", "sidecar"),
+		},
+		{
+			name: "pod labeled as EmptyDir",
+			pod: newPod(map[string]string{
+				constants.PodLabelFluentPVCName: fpvc.Name,
+				constants.PodLabelDegraded:      "EmptyDir",
+			}, "app", "sidecar"),
+			want: true,
+		},
+		{
+			name: "pod labeled as Unmodified",
+			pod: newPod(map[string]string{
+				constants.PodLabelFluentPVCName: fpvc.Name,
+				constants.PodLabelDegraded:      "Unmodified",
+			}, "app"),
+			want: true,
+		},
+		{
+			name: "pod admitted while the pod webhook is unavailable",
+			pod:  newPod(map[string]string{constants.PodLabelFluentPVCName: fpvc.Name}, "app"),
+			want: true,
+		},
+		{
+			name: "pod of a missing fluentpvc",
+			pod:  newPod(map[string]string{constants.PodLabelFluentPVCName: "missing"}, "app"),
+		},
+		{
+			name: "pod without the fluentpvc",
+			pod:  newPod(nil, "app"),
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			r := &fluentPVCReconciler{Client: newTestClient(t, fpvc.DeepCopy()), Scheme: newTestScheme(t)}
+			requests := r.mapDegradedPodToFluentPVC(c.pod)
+			if !c.want {
+				if len(requests) != 0 {
+					t.Errorf("expected no requests, but got %+v", requests)
+				}
+				return
+			}
+			if len(requests) != 1 || requests[0].Name != fpvc.Name {
+				t.Errorf("expected the request of fluentpvc='%s', but got %+v", fpvc.Name, requests)
+			}
+		})
+	}
+}
@@ -10,19 +10,27 @@ import (
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
 	"github.com/st-tech/fluent-pvc-operator/constants"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+	revisionutils "github.com/st-tech/fluent-pvc-operator/utils/revision"
 )
 
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs,verbs=get;list;watch
@@ -30,18 +38,25 @@ import (
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
-//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcrevisions,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=list
+//+kubebuilder:rbac:groups="storage.k8s.io",resources=volumeattachments,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;delete
 
 type fluentPVCBindingReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
 }
 
 func NewFluentPVCBindingReconciler(mgr ctrl.Manager) *fluentPVCBindingReconciler {
 	return &fluentPVCBindingReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Scheme:    mgr.GetScheme(),
 	}
 }
 
@@ -50,6 +65,9 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	b := &fluentpvcv1alpha1.FluentPVCBinding{}
 	if err := r.Get(ctx, req.NamespacedName, b); err != nil {
 		if apierrors.IsNotFound(err) {
+			if err := r.provisionFluentPVCBinding(ctx, req); err != nil {
+				return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
@@ -67,6 +85,14 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
+	if _, ok := b.Annotations[constants.FluentPVCBindingAnnotationAction]; ok {
+		if err := r.processAction(ctx, b); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		// NOTE: Wait until next #Reconcile to avoid update confliction.
+		return ctrl.Result{}, nil
+	}
+
 	if b.IsConditionUnknown() {
 		logger.Info(fmt.Sprintf("Skip processing because fluentpvcbinding='%s' is unknown status.", b.Name))
 		return ctrl.Result{}, nil
@@ -115,7 +141,7 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	if !podFound && !b.IsConditionReady() {
-		if isCreatedBefore(b, 1*time.Hour) { // TODO: make it configurable?
+		if isBindingPodWaitedBefore(b, 1*time.Hour) { // TODO: make it configurable?
 			if err := r.updateConditionPodMissingBindingPodTimeout(ctx, b); err != nil {
 				return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 			}
@@ -137,7 +163,7 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			}
 			return ctrl.Result{}, nil
 		}
-		if err := r.updateConditionByFinalizerJobStatus(ctx, b); err != nil {
+		if err := r.updateConditionByFinalizerJobStatus(ctx, b, fpvc); err != nil {
 			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 		}
 	case podFound && !pvcFound:
@@ -166,6 +192,28 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}
 		switch pod.Status.Phase {
 		case corev1.PodPending, corev1.PodRunning, corev1.PodUnknown:
+			if fpvc.Spec.NodeFailureHandling.Policy == fluentpvcv1alpha1.FluentPVCNodeFailurePolicyForceDeletePod {
+				if b.IsConditionOutOfUse() {
+					// NOTE: The pod is regarded as gone because the node is lost, but the pod may remain
+					//       if the force deletion is failed or the pod has finalizers.
+					if err := r.forceDeletePod(ctx, b, pod); err != nil {
+						return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+					}
+					if err := r.updateConditionByFinalizerJobStatus(ctx, b, fpvc); err != nil {
+						return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+					}
+					return ctrl.Result{}, nil
+				}
+				if lost, message, err := r.isNodeLost(ctx, pod.Spec.NodeName, nodeFailureGracePeriod(fpvc)); err != nil {
+					return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+				} else if lost {
+					if err := r.updateConditionOutOfUseNodeLost(ctx, b, pod, message); err != nil {
+						return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+					}
+					// NOTE: Force-delete the pod in the next #Reconcile to avoid update confliction.
+					return ctrl.Result{}, nil
+				}
+			}
 			logger.Info(fmt.Sprintf(
 				"Skip processing because pod='%s'(UID='%s') is '%s' phase and pvc='%s'(UID='%s') is found",
 				b.Spec.Pod.Name, b.Spec.Pod.UID, pod.Status.Phase, b.Spec.PVC.Name, b.Spec.PVC.UID,
@@ -178,7 +226,7 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 				// NOTE: Wait until a finalizer job is applied.
 				return ctrl.Result{}, nil
 			}
-			if err := r.updateConditionByFinalizerJobStatus(ctx, b); err != nil {
+			if err := r.updateConditionByFinalizerJobStatus(ctx, b, fpvc); err != nil {
 				return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 			}
 		}
@@ -187,6 +235,99 @@ func (r *fluentPVCBindingReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	return ctrl.Result{}, nil
 }
 
+// provisionFluentPVCBinding creates the PVC and the fluentpvcbinding for the pod whose PVC is injected by
+// the webhook without being provisioned. See FluentPVCProvisioningModeController.
+func (r *fluentPVCBindingReconciler) provisionFluentPVCBinding(ctx context.Context, req ctrl.Request) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("provisionFluentPVCBinding")
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, &client.ListOptions{
+		Namespace: req.Namespace,
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			constants.PodLabelFluentPVCBindingName: req.Name,
+		}),
+	}); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil
+	}
+	if len(pods.Items) != 1 {
+		return xerrors.New(fmt.Sprintf("Illegal number of pods(n=%d) is found.", len(pods.Items)))
+	}
+	pod := &pods.Items[0]
+	if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		logger.Info(fmt.Sprintf("Skip provisioning because pod='%s' is already terminated.", pod.Name))
+		return nil
+	}
+	fpvcName, ok := pod.Labels[constants.PodLabelFluentPVCName]
+	if !ok {
+		return nil
+	}
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := r.Get(ctx, client.ObjectKey{Name: fpvcName}, fpvc); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if fpvc.Spec.ProvisioningMode != fluentpvcv1alpha1.FluentPVCProvisioningModeController {
+		return nil
+	}
+	podHasPVC := false
+	for _, v := range pod.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == req.Name {
+			podHasPVC = true
+		}
+	}
+	if !podHasPVC {
+		return xerrors.New(fmt.Sprintf("pod='%s' does not have pvc='%s'.", pod.Name, req.Name))
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, req.NamespacedName, pvc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Create pvc='%s'(namespace='%s') for pod='%s'.", req.Name, req.Namespace, pod.Name))
+		pvc.SetName(req.Name)
+		pvc.SetNamespace(req.Namespace)
+		pvc.Spec = *fpvc.Spec.PVCSpecTemplate.DeepCopy()
+		controllerutil.AddFinalizer(pvc, constants.PVCFinalizerName)
+		// NOTE: fluentpvcbinding does not own pvc for preventing pvc from becoming terminating when fluentpvcbinding
+		//       is deleted. This is because the finalizer job cannot mount the pvc if it is terminating.
+		if err := r.Create(ctx, pvc); err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if !controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName) {
+		return xerrors.New(fmt.Sprintf("pvc='%s' exists, but it is not provisioned by fluent-pvc-operator.", pvc.Name))
+	}
+
+	// NOTE: The spec of the fluentpvc may be changed after the pod is admitted, but the current revision is
+	//       the best guess because the webhook does not write any objects in this mode.
+	rev, err := revisionutils.GetOrCreateFluentPVCRevision(ctx, r.Client, r.Scheme, fpvc)
+	if err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Create fluentpvcbinding='%s'(namespace='%s') for pod='%s'.", req.Name, req.Namespace, pod.Name))
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	b.SetName(req.Name)
+	b.SetNamespace(req.Namespace)
+	b.SetFluentPVC(fpvc)
+	b.SetFluentPVCRevision(rev)
+	b.SetPod(pod)
+	b.SetPVC(pvc)
+	controllerutil.AddFinalizer(b, constants.FluentPVCBindingFinalizerName)
+	if err := ctrl.SetControllerReference(fpvc, b, r.Scheme); err != nil {
+		return xerrors.Errorf("Unexpected error occurred: %w", err)
+	}
+	if err := r.Create(ctx, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	b.SetPhasePending()
+	if err := r.Status().Update(ctx, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return nil
+}
+
 func (r *fluentPVCBindingReconciler) fulfillFluentPVCBindingPod(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, pod *corev1.Pod) (bool, error) {
 	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("fulfillFluentPVCBindingPod")
 	podFound := true
@@ -248,9 +389,29 @@ func (r *fluentPVCBindingReconciler) fulfillFluentPVCBindingPVC(ctx context.Cont
 		logger.Info(fmt.Sprintf("pvc.UID='%s' is different from the binding pvc.UID='%s' for name='%s'.", pvc.UID, b.Spec.PVC.UID, b.Name))
 		pvcFound = false
 	}
+	if !pvcFound && b.Status.FinalizerPVC != nil {
+		// NOTE: The pvc is released after it is restored into the finalizer pvc, so regard the finalizer pvc as
+		//       the pvc. See FluentPVCFinalizationModeSnapshot.
+		return r.fulfillFinalizerPVC(ctx, b, pvc)
+	}
 	return pvcFound, nil
 }
 
+func (r *fluentPVCBindingReconciler) fulfillFinalizerPVC(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("fulfillFinalizerPVC")
+	if err := r.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: b.Status.FinalizerPVC.Name}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if !b.IsFinalizerPVC(pvc) {
+		logger.Info(fmt.Sprintf("pvc.UID='%s' is different from the finalizer pvc.UID='%s' for name='%s'.", pvc.UID, b.Status.FinalizerPVC.UID, b.Name))
+		return false, nil
+	}
+	return true, nil
+}
+
 func (r *fluentPVCBindingReconciler) updateControllerFluentPVC(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, fpvc *fluentpvcv1alpha1.FluentPVC) error {
 	if err := ctrl.SetControllerReference(fpvc, b, r.Scheme); err != nil {
 		return xerrors.Errorf("Unexpected error occurred: %w", err)
@@ -308,6 +469,154 @@ func (r *fluentPVCBindingReconciler) deleteFluentPVCBinding(ctx context.Context,
 	return nil
 }
 
+// processAction processes the manual remediation requested by the action annotation, records it, and then
+// removes the annotations. See FluentPVCBindingAction.
+func (r *fluentPVCBindingReconciler) processAction(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("processAction")
+	action := fluentpvcv1alpha1.FluentPVCBindingAction(b.Annotations[constants.FluentPVCBindingAnnotationAction])
+	requestedBy, ok := b.Annotations[constants.FluentPVCBindingAnnotationActionRequestedBy]
+	if !ok {
+		// NOTE: The requester is recorded by the webhook, so the action is admitted while the webhook is unavailable.
+		//       The action is dropped because it may be unknown or spoofed, and it has to be requested again.
+		logger.Info(fmt.Sprintf("Drop action='%s' for fluentpvcbinding='%s' because it is not admitted by the webhook.", action, b.Name))
+		delete(b.Annotations, constants.FluentPVCBindingAnnotationAction)
+		if err := r.Update(ctx, b); err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		return nil
+	}
+	message := fmt.Sprintf("Action='%s' is requested by '%s' for fluentpvcbinding='%s'.", action, requestedBy, b.Name)
+	logger.Info(message)
+
+	switch action {
+	case fluentpvcv1alpha1.FluentPVCBindingActionRetry:
+		if b.IsConditionUnknown() {
+			b.SetConditionNotUnknown("ActionRetry", message)
+		}
+	case fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer:
+		jobs := &batchv1.JobList{}
+		if err := r.List(ctx, jobs, client.InNamespace(b.Namespace), matchingOwnerControllerField(b.Name)); err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		for i := range jobs.Items {
+			j := &jobs.Items[i]
+			logger.Info(fmt.Sprintf("Delete the finalizer job='%s' of fluentpvcbinding='%s'.", j.Name, b.Name))
+			if err := r.Delete(ctx, j, deleteOptionsBackground(&j.UID, &j.ResourceVersion)); client.IgnoreNotFound(err) != nil {
+				return xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+		}
+		if b.IsConditionUnknown() {
+			b.SetConditionNotUnknown("ActionRerunFinalizer", message)
+		}
+		if b.IsConditionFinalizerJobApplied() {
+			b.SetConditionNotFinalizerJobApplied("ActionRerunFinalizer", message)
+		}
+		if b.IsConditionFinalizerJobFailed() {
+			b.SetConditionNotFinalizerJobFailed("ActionRerunFinalizer", message)
+		}
+		if b.IsConditionFinalizerJobSucceeded() {
+			b.SetConditionNotFinalizerJobSucceeded("ActionRerunFinalizer", message)
+		}
+		if b.IsConditionFinalizerJobMultiAttached() {
+			b.SetConditionNotFinalizerJobMultiAttached("ActionRerunFinalizer", message)
+		}
+		b.Status.FinalizerJobResult = nil
+	case fluentpvcv1alpha1.FluentPVCBindingActionRelease:
+		return r.releaseFluentPVCBinding(ctx, b)
+	default:
+		logger.Info(fmt.Sprintf("Ignore the unknown action='%s' for fluentpvcbinding='%s'.", action, b.Name))
+	}
+
+	b.SetLastAction(action, requestedBy)
+	if err := r.Status().Update(ctx, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	delete(b.Annotations, constants.FluentPVCBindingAnnotationAction)
+	delete(b.Annotations, constants.FluentPVCBindingAnnotationActionRequestedBy)
+	if err := r.Update(ctx, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return nil
+}
+
+// releaseFluentPVCBinding removes the finalizers of the pvc and the fluentpvcbinding without finalization,
+// and deletes the fluentpvcbinding. The pvc is kept so that the data can be recovered by hand.
+func (r *fluentPVCBindingReconciler) releaseFluentPVCBinding(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("releaseFluentPVCBinding")
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: b.Spec.PVC.Name}, pvc); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if b.IsBindingPVC(pvc) && controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName) {
+		logger.Info(fmt.Sprintf("Remove the finalizer='%s' from pvc='%s' to release it.", constants.PVCFinalizerName, pvc.Name))
+		controllerutil.RemoveFinalizer(pvc, constants.PVCFinalizerName)
+		if err := r.Update(ctx, pvc); err != nil {
+			return xerrors.Errorf("Failed to remove finalizer from PVC='%s'.: %w", pvc.Name, err)
+		}
+	}
+	if controllerutil.ContainsFinalizer(b, constants.FluentPVCBindingFinalizerName) {
+		logger.Info(fmt.Sprintf("Remove the finalizer of fluentpvcbinding='%s' to release it.", b.Name))
+		controllerutil.RemoveFinalizer(b, constants.FluentPVCBindingFinalizerName)
+		if err := r.Update(ctx, b); client.IgnoreNotFound(err) != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	}
+	logger.Info(fmt.Sprintf("Delete fluentpvcbinding='%s' because it is released.", b.Name))
+	if err := r.Delete(ctx, b, deleteOptionsBackground(&b.UID, &b.ResourceVersion)); client.IgnoreNotFound(err) != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return nil
+}
+
+// isNodeLost returns true with the reason if the node is deleted, or has been NotReady for longer than
+// the grace period.
+func (r *fluentPVCBindingReconciler) isNodeLost(ctx context.Context, nodeName string, gracePeriod time.Duration) (bool, string, error) {
+	if nodeName == "" {
+		return false, "", nil
+	}
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, fmt.Sprintf("node='%s' is deleted", nodeName), nil
+		}
+		return false, "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	c := findNodeCondition(node, corev1.NodeReady)
+	if c == nil || c.Status == corev1.ConditionTrue {
+		return false, "", nil
+	}
+	if !isTransitionedBefore(c.LastTransitionTime, gracePeriod) {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("node='%s' has been NotReady(status='%s', reason='%s') since %s", node.Name, c.Status, c.Reason, c.LastTransitionTime), nil
+}
+
+// forceDeletePod deletes the pod without the graceful termination. The kubelet of the lost node can no longer
+// terminate the pod, and the volume is not detached from the node until the pod is deleted.
+func (r *fluentPVCBindingReconciler) forceDeletePod(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, pod *corev1.Pod) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("forceDeletePod")
+	if !pod.DeletionTimestamp.IsZero() && pod.DeletionGracePeriodSeconds != nil && *pod.DeletionGracePeriodSeconds == 0 {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("Force-delete pod='%s' on the lost node='%s' for fluentpvcbinding='%s'.", pod.Name, b.Status.LostNodeName, b.Name))
+	opts := deleteOptionsBackground(&pod.UID, nil)
+	opts.GracePeriodSeconds = pointer.Int64Ptr(0)
+	if err := r.Delete(ctx, pod, opts); client.IgnoreNotFound(err) != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return nil
+}
+
+func (r *fluentPVCBindingReconciler) updateConditionOutOfUseNodeLost(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, pod *corev1.Pod, reason string) error {
+	message := fmt.Sprintf(
+		"pod='%s'(UID='%s') is regarded as gone because %s, and pvc='%s'(UID='%s') is found.(fluentpvcbinding='%s')",
+		b.Spec.Pod.Name, b.Spec.Pod.UID, reason, b.Spec.PVC.Name, b.Spec.PVC.UID, b.Name,
+	)
+	b.Status.LostNodeName = pod.Spec.NodeName
+	return r.updateConditionOutOfUse(ctx, b, "NodeLost", message)
+}
+
 func (r *fluentPVCBindingReconciler) updateConditionUnknownPVCLost(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding) error {
 	message := fmt.Sprintf("pvc='%s'(UID='%s') is lost.(fluentpvcbinding='%s')", b.Spec.PVC.Name, b.Spec.PVC.UID, b.Name)
 	return r.updateConditionUnknown(ctx, b, "PVCLost", message)
@@ -401,13 +710,22 @@ func (r *fluentPVCBindingReconciler) updateCondition(ctx context.Context, b *flu
 	return nil
 }
 
-func (r *fluentPVCBindingReconciler) updateConditionByFinalizerJobStatus(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding) error {
+func (r *fluentPVCBindingReconciler) updateConditionByFinalizerJobStatus(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, fpvc *fluentpvcv1alpha1.FluentPVC) error {
 	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("updateConditionByFinalizerJobStatus")
 	logger.Info(fmt.Sprintf("Check the finalizer jobs for fluentpvcbinding='%s'.", b.Name))
 	jobs := &batchv1.JobList{}
 	if err := r.List(ctx, jobs, matchingOwnerControllerField(b.Name)); client.IgnoreNotFound(err) != nil {
 		return xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
+	// NOTE: Ignore the jobs being deleted (e.g. by the rerun-finalizer action), so that they are not regarded
+	//       as multiple finalizer jobs.
+	alive := []batchv1.Job{}
+	for _, j := range jobs.Items {
+		if j.DeletionTimestamp.IsZero() {
+			alive = append(alive, j)
+		}
+	}
+	jobs.Items = alive
 	if len(jobs.Items) == 0 {
 		reason := "FinalizerJobNotFound"
 		message := fmt.Sprintf("Finalizer jobs for fluentpvcbinding='%s' is not found.", b.Name)
@@ -420,6 +738,11 @@ func (r *fluentPVCBindingReconciler) updateConditionByFinalizerJobStatus(ctx con
 			b.SetConditionNotFinalizerJobFailed(reason, message)
 			needUpdate = true
 		}
+		if b.Status.FinalizerJobResult != nil {
+			// NOTE: The result is reported by the deleted job.
+			b.Status.FinalizerJobResult = nil
+			needUpdate = true
+		}
 		if needUpdate {
 			if err := r.Status().Update(ctx, b); err != nil {
 				return xerrors.Errorf("Unexpected error occurred.: %w", err)
@@ -450,13 +773,24 @@ func (r *fluentPVCBindingReconciler) updateConditionByFinalizerJobStatus(ctx con
 		logger.Info(message)
 		b.SetConditionFinalizerJobApplied("FinalizerJobFound", message)
 	}
-	if isJobSucceeded(j) {
+	if finished, _ := jobutils.GetFinishedStatus(j); finished && b.Status.FinalizerJobResult == nil {
+		result, err := r.findFinalizerJobResult(ctx, j)
+		if err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if result != nil {
+			needUpdate = true
+			logger.Info(fmt.Sprintf("Record the result of the finalizer job='%s' reported by pod='%s' for fluentpvcbinding='%s'.", j.Name, result.PodName, b.Name))
+			b.Status.FinalizerJobResult = result
+		}
+	}
+	if jobutils.IsSucceeded(j) {
 		needUpdate = true
 		message := fmt.Sprintf("Update the status fluentpvcbinding='%s' 'FinalizerJobSucceeded' because the finalizer job='%s' is succeeded", b.Name, j.Name)
 		logger.Info(message)
 		b.SetConditionFinalizerJobSucceeded("FinalizerJobSucceeded", message)
 	}
-	if isJobFailed(j) {
+	if jobutils.IsFailed(j) {
 		needUpdate = true
 		message := fmt.Sprintf("Update the status fluentpvcbinding='%s' 'FinalizerJobFailed' because the finalizer job='%s' is failed.", b.Name, j.Name)
 		logger.Info(message)
@@ -467,9 +801,146 @@ func (r *fluentPVCBindingReconciler) updateConditionByFinalizerJobStatus(ctx con
 			return xerrors.Errorf("Unexpected error occurred.: %w", err)
 		}
 	}
+	if err := r.updateConditionByVolumeAttachments(ctx, b, fpvc, j); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
 	return nil
 }
 
+// findFinalizerJobResult returns the result reported by the pods of the finished job. The succeeded pods are
+// preferred to the failed pods, and then the latest attempt is preferred.
+func (r *fluentPVCBindingReconciler) findFinalizerJobResult(ctx context.Context, j *batchv1.Job) (*fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult, error) {
+	pods, err := r.listJobPods(ctx, j)
+	if err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return jobutils.FindResult(pods), nil
+}
+
+func (r *fluentPVCBindingReconciler) listJobPods(ctx context.Context, j *batchv1.Job) ([]corev1.Pod, error) {
+	if j.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(j.Spec.Selector)
+	if err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(j.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return pods.Items, nil
+}
+
+// updateConditionByVolumeAttachments detects the finalizer job pod that cannot attach the volume because
+// the VolumeAttachment of the PVC is left on another node (e.g. the lost node of the pod), and force-detaches
+// the volume by deleting the VolumeAttachment if it is allowed. Each step is reported as the condition.
+func (r *fluentPVCBindingReconciler) updateConditionByVolumeAttachments(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding, fpvc *fluentpvcv1alpha1.FluentPVC, j *batchv1.Job) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingReconciler").WithName("updateConditionByVolumeAttachments")
+	var pod *corev1.Pod
+	if finished, _ := jobutils.GetFinishedStatus(j); !finished {
+		p, err := r.findMultiAttachedPod(ctx, j)
+		if err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		pod = p
+	}
+	if pod == nil {
+		if b.IsConditionFinalizerJobMultiAttached() {
+			message := fmt.Sprintf("The finalizer job='%s' of fluentpvcbinding='%s' has no Multi-Attach errors.", j.Name, b.Name)
+			return r.updateCondition(ctx, b, "MultiAttachResolved", message, b.SetConditionNotFinalizerJobMultiAttached)
+		}
+		return nil
+	}
+
+	pvcName := b.Spec.PVC.Name
+	if b.Status.FinalizerPVC != nil {
+		pvcName = b.Status.FinalizerPVC.Name
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: b.Namespace, Name: pvcName}, pvc); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	vas := &storagev1.VolumeAttachmentList{}
+	if err := r.List(ctx, vas); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	reason := "VolumeAttachmentNotFound"
+	messages := []string{fmt.Sprintf(
+		"pod='%s' of the finalizer job='%s' cannot attach pvc='%s'(volume='%s') because of the Multi-Attach error.",
+		pod.Name, j.Name, pvc.Name, pvc.Spec.VolumeName,
+	)}
+	for i := range vas.Items {
+		va := &vas.Items[i]
+		if va.Spec.Source.PersistentVolumeName == nil || *va.Spec.Source.PersistentVolumeName != pvc.Spec.VolumeName {
+			continue
+		}
+		if va.Spec.NodeName == pod.Spec.NodeName {
+			continue
+		}
+		lost, lostReason, err := r.isNodeLost(ctx, va.Spec.NodeName, nodeFailureGracePeriod(fpvc))
+		if err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		switch {
+		case !lost:
+			reason = "WaitingForNodeLost"
+			messages = append(messages, fmt.Sprintf("volumeattachment='%s' is on node='%s', but the node is not confirmed lost.", va.Name, va.Spec.NodeName))
+		case !fpvc.Spec.NodeFailureHandling.ForceDetachVolumes:
+			reason = "ForceDetachDisabled"
+			messages = append(messages, fmt.Sprintf(
+				"volumeattachment='%s' is left because %s, but nodeFailureHandling.forceDetachVolumes of fluentpvc='%s' is disabled.",
+				va.Name, lostReason, fpvc.Name,
+			))
+		case !va.DeletionTimestamp.IsZero():
+			reason = "VolumeAttachmentDeleting"
+			messages = append(messages, fmt.Sprintf("volumeattachment='%s' is being deleted because %s.", va.Name, lostReason))
+		default:
+			logger.Info(fmt.Sprintf("Delete volumeattachment='%s' of pvc='%s' because %s.", va.Name, pvc.Name, lostReason))
+			if err := r.Delete(ctx, va, deleteOptionsBackground(&va.UID, &va.ResourceVersion)); client.IgnoreNotFound(err) != nil {
+				return xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			reason = "VolumeAttachmentDeleted"
+			messages = append(messages, fmt.Sprintf("volumeattachment='%s' is deleted because %s.", va.Name, lostReason))
+		}
+	}
+	message := strings.Join(messages, " ")
+	if c := meta.FindStatusCondition(b.Status.Conditions, string(fluentpvcv1alpha1.FluentPVCBindingConditionFinalizerJobMultiAttached)); c != nil &&
+		c.Status == metav1.ConditionTrue && c.Reason == reason && c.Message == message {
+		return nil
+	}
+	return r.updateCondition(ctx, b, reason, message, b.SetConditionFinalizerJobMultiAttached)
+}
+
+// findMultiAttachedPod returns the pending pod of the job that has the FailedAttachVolume event of
+// the Multi-Attach error, or nil if it is not found.
+func (r *fluentPVCBindingReconciler) findMultiAttachedPod(ctx context.Context, j *batchv1.Job) (*corev1.Pod, error) {
+	pods, err := r.listJobPods(ctx, j)
+	if err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodPending || pod.Spec.NodeName == "" {
+			continue
+		}
+		// NOTE: Read events from the API server directly not to cache all the events in the cluster.
+		events := &corev1.EventList{}
+		if err := r.APIReader.List(ctx, events, client.InNamespace(pod.Namespace), client.MatchingFields{
+			"involvedObject.uid": string(pod.UID),
+			"reason":             "FailedAttachVolume",
+		}); err != nil {
+			return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		for _, e := range events.Items {
+			if strings.Contains(e.Message, "Multi-Attach") {
+				return pod, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 func (r *fluentPVCBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	ctx := context.Background()
 	if err := mgr.GetFieldIndexer().IndexField(
@@ -480,6 +951,14 @@ func (r *fluentPVCBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	); err != nil {
 		return xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
+	if err := mgr.GetFieldIndexer().IndexField(
+		ctx,
+		&corev1.Pod{},
+		constants.PodNodeNameField,
+		indexPodByNodeName,
+	); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
 	ch := make(chan event.GenericEvent)
 	watcher := &fluentPVCBindingWatcher{
 		client:    mgr.GetClient(),
@@ -503,9 +982,34 @@ func (r *fluentPVCBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&batchv1.Job{}).
 		WithEventFilter(pred).
 		Watches(&src, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(mapPodToFluentPVCBinding)).
+		// NOTE: The grace period of the NotReady nodes and the deleted nodes are checked by fluentPVCBindingWatcher.
+		Watches(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(r.mapNodeToFluentPVCBindings)).
 		Complete(r)
 }
 
+func mapPodToFluentPVCBinding(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[constants.PodLabelFluentPVCBindingName]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+func (r *fluentPVCBindingReconciler) mapNodeToFluentPVCBindings(obj client.Object) []reconcile.Request {
+	logger := ctrl.Log.WithName("fluentPVCBindingReconciler").WithName("mapNodeToFluentPVCBindings")
+	pods := &corev1.PodList{}
+	if err := r.List(context.Background(), pods, client.MatchingFields{constants.PodNodeNameField: obj.GetName()}); err != nil {
+		logger.Error(err, fmt.Sprintf("Cannot list the pods on node='%s'.", obj.GetName()))
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for i := range pods.Items {
+		requests = append(requests, mapPodToFluentPVCBinding(&pods.Items[i])...)
+	}
+	return requests
+}
+
 type fluentPVCBindingWatcher struct {
 	client    client.Client
 	ch        chan<- event.GenericEvent
@@ -0,0 +1,741 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+)
+
+func TestProcessAction(t *testing.T) {
+	const (
+		namespace   = "default"
+		requestedBy = "admin"
+	)
+	newBinding := func(action fluentpvcv1alpha1.FluentPVCBindingAction, annotateRequester bool) *fluentpvcv1alpha1.FluentPVCBinding {
+		b := &fluentpvcv1alpha1.FluentPVCBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "binding",
+				Namespace:   namespace,
+				UID:         "binding-uid",
+				Annotations: map[string]string{constants.FluentPVCBindingAnnotationAction: string(action)},
+				Finalizers:  []string{constants.FluentPVCBindingFinalizerName},
+			},
+			Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+				PVC: fluentpvcv1alpha1.ObjectIdentity{Name: "binding", UID: "pvc-uid"},
+			},
+		}
+		if annotateRequester {
+			b.Annotations[constants.FluentPVCBindingAnnotationActionRequestedBy] = requestedBy
+		}
+		b.SetConditionUnknown("PVCLost", "pvc is lost")
+		b.SetConditionFinalizerJobApplied("FinalizerJobApplied", "finalizer job is applied")
+		b.SetConditionFinalizerJobFailed("FinalizerJobFailed", "finalizer job is failed")
+		b.Status.FinalizerJobResult = &fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult{DestinationURI: "s3://bucket"}
+		return b
+	}
+	newPVC := func(uid string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "binding",
+				Namespace:  namespace,
+				UID:        types.UID(uid),
+				Finalizers: []string{constants.PVCFinalizerName},
+			},
+		}
+	}
+	finalizerJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "binding-finalizer",
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: fluentpvcv1alpha1.GroupVersion.String(),
+				Kind:       "FluentPVCBinding",
+				Name:       "binding",
+				UID:        "binding-uid",
+				Controller: func(b bool) *bool { return &b }(true),
+			}},
+		},
+	}
+	cases := []struct {
+		name                       string
+		binding                    *fluentpvcv1alpha1.FluentPVCBinding
+		objects                    []client.Object
+		wantDeleted                bool
+		wantLastAction             fluentpvcv1alpha1.FluentPVCBindingAction
+		wantUnknown                bool
+		wantFinalizerJobConditions bool
+		wantFinalizerJobResult     bool
+		wantJobDeleted             bool
+		wantPVCFinalizer           bool
+	}{
+		{
+			name:                       "retry",
+			binding:                    newBinding(fluentpvcv1alpha1.FluentPVCBindingActionRetry, true),
+			wantLastAction:             fluentpvcv1alpha1.FluentPVCBindingActionRetry,
+			wantFinalizerJobConditions: true,
+			wantFinalizerJobResult:     true,
+		},
+		{
+			name:           "rerun-finalizer",
+			binding:        newBinding(fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer, true),
+			wantLastAction: fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer,
+			wantJobDeleted: true,
+		},
+		{
+			name:        "release",
+			binding:     newBinding(fluentpvcv1alpha1.FluentPVCBindingActionRelease, true),
+			objects:     []client.Object{newPVC("pvc-uid")},
+			wantDeleted: true,
+		},
+		{
+			name:             "release keeping the pvc of another binding",
+			binding:          newBinding(fluentpvcv1alpha1.FluentPVCBindingActionRelease, true),
+			objects:          []client.Object{newPVC("another-pvc-uid")},
+			wantDeleted:      true,
+			wantPVCFinalizer: true,
+		},
+		{
+			name:                       "unknown action",
+			binding:                    newBinding("unknown", true),
+			wantLastAction:             "unknown",
+			wantUnknown:                true,
+			wantFinalizerJobConditions: true,
+			wantFinalizerJobResult:     true,
+		},
+		{
+			name:                       "action without the requester",
+			binding:                    newBinding(fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer, false),
+			wantUnknown:                true,
+			wantFinalizerJobConditions: true,
+			wantFinalizerJobResult:     true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			objects := append([]client.Object{c.binding.DeepCopy(), finalizerJob.DeepCopy()}, c.objects...)
+			r := &fluentPVCBindingReconciler{Client: newTestClient(t, objects...)}
+			ctx := context.Background()
+			b := &fluentpvcv1alpha1.FluentPVCBinding{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(c.binding), b); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.processAction(ctx, b); err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+
+			err := r.Get(ctx, client.ObjectKeyFromObject(finalizerJob), &batchv1.Job{})
+			if c.wantJobDeleted != apierrors.IsNotFound(err) {
+				t.Errorf("expected the finalizer job deleted=%t, but got %+v", c.wantJobDeleted, err)
+			}
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "binding"}, pvc); err == nil {
+				if got := controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName); got != c.wantPVCFinalizer {
+					t.Errorf("expected the pvc finalizer=%t, but got %t", c.wantPVCFinalizer, got)
+				}
+			} else if !apierrors.IsNotFound(err) {
+				t.Fatal(err)
+			}
+
+			b = &fluentpvcv1alpha1.FluentPVCBinding{}
+			err = r.Get(ctx, client.ObjectKeyFromObject(c.binding), b)
+			if c.wantDeleted {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected fluentpvcbinding to be deleted, but got %+v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected fluentpvcbinding to be kept, but got %+v", err)
+			}
+			for _, k := range []string{
+				constants.FluentPVCBindingAnnotationAction,
+				constants.FluentPVCBindingAnnotationActionRequestedBy,
+			} {
+				if _, ok := b.Annotations[k]; ok {
+					t.Errorf("expected the annotation='%s' to be removed, but got %+v", k, b.Annotations)
+				}
+			}
+			if c.wantLastAction == "" {
+				if b.Status.LastAction != nil {
+					t.Errorf("expected no last action, but got %+v", b.Status.LastAction)
+				}
+			} else if b.Status.LastAction == nil ||
+				b.Status.LastAction.Action != c.wantLastAction ||
+				b.Status.LastAction.RequestedBy != requestedBy {
+				t.Errorf("expected the last action='%s' requested by '%s', but got %+v", c.wantLastAction, requestedBy, b.Status.LastAction)
+			}
+			if got := b.IsConditionUnknown(); got != c.wantUnknown {
+				t.Errorf("expected Unknown=%t, but got %t", c.wantUnknown, got)
+			}
+			if got := b.IsConditionFinalizerJobApplied() && b.IsConditionFinalizerJobFailed(); got != c.wantFinalizerJobConditions {
+				t.Errorf("expected FinalizerJobApplied and FinalizerJobFailed=%t, but got %+v", c.wantFinalizerJobConditions, b.Status.Conditions)
+			}
+			if got := b.Status.FinalizerJobResult != nil; got != c.wantFinalizerJobResult {
+				t.Errorf("expected the finalizer job result=%t, but got %+v", c.wantFinalizerJobResult, b.Status.FinalizerJobResult)
+			}
+		})
+	}
+}
+
+func TestReconcileNodeFailure(t *testing.T) {
+	const (
+		namespace = "default"
+		nodeName  = "node"
+	)
+	newFluentPVC := func(policy fluentpvcv1alpha1.FluentPVCNodeFailurePolicy) *fluentpvcv1alpha1.FluentPVC {
+		return &fluentpvcv1alpha1.FluentPVC{
+			ObjectMeta: metav1.ObjectMeta{Name: "fluent-pvc", UID: "fluent-pvc-uid"},
+			Spec: fluentpvcv1alpha1.FluentPVCSpec{
+				NodeFailureHandling: fluentpvcv1alpha1.FluentPVCNodeFailureHandling{
+					Policy:             policy,
+					GracePeriodSeconds: pointer.Int64Ptr(300),
+				},
+			},
+		}
+	}
+	newNode := func(status corev1.ConditionStatus, since time.Duration) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{
+					Type:               corev1.NodeReady,
+					Status:             status,
+					Reason:             "KubeletStopped",
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-since)),
+				}},
+			},
+		}
+	}
+	cases := []struct {
+		name           string
+		policy         fluentpvcv1alpha1.FluentPVCNodeFailurePolicy
+		node           *corev1.Node
+		wantPodDeleted bool
+		wantNodeLost   bool
+	}{
+		{
+			name:           "node NotReady past the grace period",
+			policy:         fluentpvcv1alpha1.FluentPVCNodeFailurePolicyForceDeletePod,
+			node:           newNode(corev1.ConditionUnknown, 10*time.Minute),
+			wantPodDeleted: true,
+			wantNodeLost:   true,
+		},
+		{
+			name:   "node NotReady within the grace period",
+			policy: fluentpvcv1alpha1.FluentPVCNodeFailurePolicyForceDeletePod,
+			node:   newNode(corev1.ConditionFalse, time.Minute),
+		},
+		{
+			name:           "node deleted",
+			policy:         fluentpvcv1alpha1.FluentPVCNodeFailurePolicyForceDeletePod,
+			wantPodDeleted: true,
+			wantNodeLost:   true,
+		},
+		{
+			name:   "node still Ready",
+			policy: fluentpvcv1alpha1.FluentPVCNodeFailurePolicyForceDeletePod,
+			node:   newNode(corev1.ConditionTrue, 10*time.Minute),
+		},
+		{
+			name:   "node NotReady past the grace period with the None policy",
+			policy: fluentpvcv1alpha1.FluentPVCNodeFailurePolicyNone,
+			node:   newNode(corev1.ConditionUnknown, 10*time.Minute),
+		},
+		{
+			name:   "node deleted with the None policy",
+			policy: fluentpvcv1alpha1.FluentPVCNodeFailurePolicyNone,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			fpvc := newFluentPVC(c.policy)
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: namespace, UID: "pod-uid"},
+				Spec: corev1.PodSpec{
+					NodeName:   nodeName,
+					Containers: []corev1.Container{{Name: "app", Image: "busybox"}},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			}
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "binding",
+					Namespace:  namespace,
+					UID:        "pvc-uid",
+					Finalizers: []string{constants.PVCFinalizerName},
+				},
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+			}
+			b := &fluentpvcv1alpha1.FluentPVCBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "binding",
+					Namespace: namespace,
+					UID:       "binding-uid",
+					OwnerReferences: []metav1.OwnerReference{
+						*metav1.NewControllerRef(fpvc, fluentpvcv1alpha1.GroupVersion.WithKind("FluentPVC")),
+					},
+				},
+			}
+			b.SetFluentPVC(fpvc)
+			b.SetPod(pod)
+			b.SetPVC(pvc)
+			b.SetConditionReady("Ready", "ready")
+			objects := []client.Object{fpvc, pod, pvc, b}
+			if c.node != nil {
+				objects = append(objects, c.node)
+			}
+			cli := newTestClient(t, objects...)
+			r := &fluentPVCBindingReconciler{Client: cli, APIReader: cli, Scheme: newTestScheme(t)}
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(b)}
+
+			// NOTE: The pod is regarded as gone in the first #Reconcile, and force-deleted in the next #Reconcile.
+			for i := 0; i < 2; i++ {
+				if _, err := r.Reconcile(ctx, req); err != nil {
+					t.Fatalf("expected no error, but got %+v", err)
+				}
+			}
+
+			got := &fluentpvcv1alpha1.FluentPVCBinding{}
+			if err := cli.Get(ctx, req.NamespacedName, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.IsConditionOutOfUse() != c.wantNodeLost {
+				t.Errorf("expected OutOfUse=%t, but got %+v", c.wantNodeLost, got.Status.Conditions)
+			}
+			if (got.Status.LostNodeName == nodeName) != c.wantNodeLost {
+				t.Errorf("expected the lost node set=%t, but got '%s'", c.wantNodeLost, got.Status.LostNodeName)
+			}
+			err := cli.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})
+			if c.wantPodDeleted != apierrors.IsNotFound(err) {
+				t.Errorf("expected the pod deleted=%t, but got %+v", c.wantPodDeleted, err)
+			}
+		})
+	}
+}
+
+// newMultiAttachedFinalizerJob returns the finalizer job of the binding, its pending pod scheduled on the node,
+// and the FailedAttachVolume event of the pod with the message.
+func newMultiAttachedFinalizerJob(namespace, nodeName, message string) (*batchv1.Job, *corev1.Pod, *corev1.Event) {
+	labels := map[string]string{"job-name": "binding-finalizer"}
+	j := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding-finalizer", Namespace: namespace, UID: "job-uid"},
+		Spec:       batchv1.JobSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding-finalizer-xxxxx", Namespace: namespace, UID: "finalizer-pod-uid", Labels: labels},
+		Spec: corev1.PodSpec{
+			NodeName:   nodeName,
+			Containers: []corev1.Container{{Name: "finalizer", Image: "busybox"}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	e := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding-finalizer-xxxxx.event", Namespace: namespace},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: namespace,
+			Name:      pod.Name,
+			UID:       pod.UID,
+		},
+		Reason:  "FailedAttachVolume",
+		Message: message,
+	}
+	return j, pod, e
+}
+
+func TestFindMultiAttachedPod(t *testing.T) {
+	const (
+		namespace          = "default"
+		multiAttachMessage = "Multi-Attach error for volume \"pv\" Volume is already exclusively attached to one node and can't be attached to another"
+	)
+	cases := []struct {
+		name    string
+		modify  func(j *batchv1.Job, pod *corev1.Pod, e *corev1.Event)
+		noEvent bool
+		wantPod bool
+	}{
+		{
+			name:    "pending pod with the Multi-Attach error",
+			wantPod: true,
+		},
+		{
+			name:    "pending pod without events",
+			noEvent: true,
+		},
+		{
+			name:   "pending pod with another attach error",
+			modify: func(j *batchv1.Job, pod *corev1.Pod, e *corev1.Event) { e.Message = "AttachVolume.Attach failed" },
+		},
+		{
+			name:   "running pod",
+			modify: func(j *batchv1.Job, pod *corev1.Pod, e *corev1.Event) { pod.Status.Phase = corev1.PodRunning },
+		},
+		{
+			name:   "pod not scheduled",
+			modify: func(j *batchv1.Job, pod *corev1.Pod, e *corev1.Event) { pod.Spec.NodeName = "" },
+		},
+		{
+			name:   "pod not selected by the job",
+			modify: func(j *batchv1.Job, pod *corev1.Pod, e *corev1.Event) { pod.Labels = nil },
+		},
+		{
+			name:   "job without the selector",
+			modify: func(j *batchv1.Job, pod *corev1.Pod, e *corev1.Event) { j.Spec.Selector = nil },
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			j, pod, e := newMultiAttachedFinalizerJob(namespace, "node", multiAttachMessage)
+			if c.modify != nil {
+				c.modify(j, pod, e)
+			}
+			objects := []client.Object{j, pod}
+			if !c.noEvent {
+				objects = append(objects, e)
+			}
+			cli := newTestClient(t, objects...)
+			r := &fluentPVCBindingReconciler{Client: cli, APIReader: cli}
+			got, err := r.findMultiAttachedPod(context.Background(), j)
+			if err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+			if c.wantPod {
+				if got == nil || got.Name != pod.Name {
+					t.Errorf("expected pod='%s', but got %+v", pod.Name, got)
+				}
+				return
+			}
+			if got != nil {
+				t.Errorf("expected no pods, but got pod='%s'", got.Name)
+			}
+		})
+	}
+}
+
+func TestUpdateConditionByVolumeAttachments(t *testing.T) {
+	const (
+		namespace = "default"
+		newNode   = "new-node"
+		lostNode  = "lost-node"
+	)
+	newVolumeAttachment := func(name, nodeName, pvName string) *storagev1.VolumeAttachment {
+		return &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: "csi.example.com",
+				NodeName: nodeName,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: pointer.StringPtr(pvName)},
+			},
+		}
+	}
+	readyNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: lostNode},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	cases := []struct {
+		name                 string
+		forceDetachVolumes   bool
+		eventMessage         string
+		jobFinished          bool
+		multiAttached        bool
+		objects              []client.Object
+		wantReason           string
+		wantMultiAttached    bool
+		wantVolumeAttachment bool
+	}{
+		{
+			name:                 "volumeattachment on the lost node",
+			forceDetachVolumes:   true,
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "pv")},
+			wantReason:           "VolumeAttachmentDeleted",
+			wantMultiAttached:    true,
+			wantVolumeAttachment: false,
+		},
+		{
+			name:                 "volumeattachment on the lost node with forceDetachVolumes disabled",
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "pv")},
+			wantReason:           "ForceDetachDisabled",
+			wantMultiAttached:    true,
+			wantVolumeAttachment: true,
+		},
+		{
+			name:                 "volumeattachment on the Ready node",
+			forceDetachVolumes:   true,
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "pv"), readyNode},
+			wantReason:           "WaitingForNodeLost",
+			wantMultiAttached:    true,
+			wantVolumeAttachment: true,
+		},
+		{
+			name:               "volumeattachment being deleted",
+			forceDetachVolumes: true,
+			objects: []client.Object{func() client.Object {
+				va := newVolumeAttachment("va", lostNode, "pv")
+				va.Finalizers = []string{"external-attacher/csi-example-com"}
+				va.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				return va
+			}()},
+			wantReason:           "VolumeAttachmentDeleting",
+			wantMultiAttached:    true,
+			wantVolumeAttachment: true,
+		},
+		{
+			name:                 "volumeattachment on the node of the finalizer job pod",
+			forceDetachVolumes:   true,
+			objects:              []client.Object{newVolumeAttachment("va", newNode, "pv")},
+			wantReason:           "VolumeAttachmentNotFound",
+			wantMultiAttached:    true,
+			wantVolumeAttachment: true,
+		},
+		{
+			name:                 "volumeattachment of another volume",
+			forceDetachVolumes:   true,
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "another-pv")},
+			wantReason:           "VolumeAttachmentNotFound",
+			wantMultiAttached:    true,
+			wantVolumeAttachment: true,
+		},
+		{
+			name:                 "no Multi-Attach errors",
+			forceDetachVolumes:   true,
+			eventMessage:         "AttachVolume.Attach failed",
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "pv")},
+			wantVolumeAttachment: true,
+		},
+		{
+			name:                 "Multi-Attach error resolved",
+			forceDetachVolumes:   true,
+			eventMessage:         "AttachVolume.Attach failed",
+			multiAttached:        true,
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "pv")},
+			wantReason:           "MultiAttachResolved",
+			wantVolumeAttachment: true,
+		},
+		{
+			name:                 "finished job",
+			forceDetachVolumes:   true,
+			jobFinished:          true,
+			multiAttached:        true,
+			objects:              []client.Object{newVolumeAttachment("va", lostNode, "pv")},
+			wantReason:           "MultiAttachResolved",
+			wantVolumeAttachment: true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			fpvc := &fluentpvcv1alpha1.FluentPVC{
+				ObjectMeta: metav1.ObjectMeta{Name: "fluent-pvc"},
+				Spec: fluentpvcv1alpha1.FluentPVCSpec{
+					NodeFailureHandling: fluentpvcv1alpha1.FluentPVCNodeFailureHandling{
+						ForceDetachVolumes: c.forceDetachVolumes,
+					},
+				},
+			}
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace, UID: "pvc-uid"},
+				Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv"},
+			}
+			b := &fluentpvcv1alpha1.FluentPVCBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace},
+			}
+			b.SetPVC(pvc)
+			if c.multiAttached {
+				b.SetConditionFinalizerJobMultiAttached("WaitingForNodeLost", "multi-attached")
+			}
+			message := c.eventMessage
+			if message == "" {
+				message = "Multi-Attach error for volume \"pv\""
+			}
+			j, pod, e := newMultiAttachedFinalizerJob(namespace, newNode, message)
+			if c.jobFinished {
+				j.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+			}
+			objects := append([]client.Object{fpvc, pvc, b, j, pod, e}, c.objects...)
+			cli := newTestClient(t, objects...)
+			r := &fluentPVCBindingReconciler{Client: cli, APIReader: cli}
+			ctx := context.Background()
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(b), b); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.updateConditionByVolumeAttachments(ctx, b, fpvc, j); err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+
+			got := &fluentpvcv1alpha1.FluentPVCBinding{}
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(b), got); err != nil {
+				t.Fatal(err)
+			}
+			if got.IsConditionFinalizerJobMultiAttached() != c.wantMultiAttached {
+				t.Errorf("expected FinalizerJobMultiAttached=%t, but got %+v", c.wantMultiAttached, got.Status.Conditions)
+			}
+			cond := meta.FindStatusCondition(got.Status.Conditions, string(fluentpvcv1alpha1.FluentPVCBindingConditionFinalizerJobMultiAttached))
+			if c.wantReason == "" {
+				if cond != nil {
+					t.Errorf("expected no FinalizerJobMultiAttached condition, but got %+v", cond)
+				}
+			} else if cond == nil || cond.Reason != c.wantReason {
+				t.Errorf("expected the reason='%s', but got %+v", c.wantReason, cond)
+			}
+			err := cli.Get(ctx, client.ObjectKey{Name: "va"}, &storagev1.VolumeAttachment{})
+			if c.wantVolumeAttachment == apierrors.IsNotFound(err) {
+				t.Errorf("expected the volumeattachment kept=%t, but got %+v", c.wantVolumeAttachment, err)
+			}
+		})
+	}
+}
+
+func TestUpdateConditionByFinalizerJobStatus(t *testing.T) {
+	const namespace = "default"
+	labels := map[string]string{"job-name": "binding-finalizer"}
+	newJob := func(condition batchv1.JobConditionType) *batchv1.Job {
+		j := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "binding-finalizer", Namespace: namespace},
+			Spec:       batchv1.JobSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		}
+		if condition != "" {
+			j.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		}
+		return j
+	}
+	newPod := func(phase corev1.PodPhase, message string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "binding-finalizer-xxxxx", Namespace: namespace, Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "finalizer", Image: "busybox"}}},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "finalizer",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: message},
+					},
+				}},
+			},
+		}
+	}
+	const resultMessage = `{"bytesShipped": 1024, "records": 10, "destinationURI": "s3://bucket/logs", "checksum": "sha256:abc"}`
+	recorded := &fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult{DestinationURI: "s3://bucket/recorded"}
+	cases := []struct {
+		name          string
+		objects       []client.Object
+		result        *fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult
+		applied       bool
+		wantResult    *fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult
+		wantApplied   bool
+		wantSucceeded bool
+		wantFailed    bool
+	}{
+		{
+			name:    "succeeded job",
+			objects: []client.Object{newJob(batchv1.JobComplete), newPod(corev1.PodSucceeded, resultMessage)},
+			wantResult: &fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult{
+				BytesShipped:   pointer.Int64Ptr(1024),
+				Records:        pointer.Int64Ptr(10),
+				DestinationURI: "s3://bucket/logs",
+				Checksum:       "sha256:abc",
+				PodName:        "binding-finalizer-xxxxx",
+				ContainerName:  "finalizer",
+			},
+			wantApplied:   true,
+			wantSucceeded: true,
+		},
+		{
+			name:    "failed job",
+			objects: []client.Object{newJob(batchv1.JobFailed), newPod(corev1.PodFailed, `{"destinationURI": "s3://bucket/logs"}`)},
+			wantResult: &fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult{
+				DestinationURI: "s3://bucket/logs",
+				PodName:        "binding-finalizer-xxxxx",
+				ContainerName:  "finalizer",
+			},
+			wantApplied: true,
+			wantFailed:  true,
+		},
+		{
+			name:          "succeeded job without the result",
+			objects:       []client.Object{newJob(batchv1.JobComplete), newPod(corev1.PodSucceeded, "done")},
+			wantApplied:   true,
+			wantSucceeded: true,
+		},
+		{
+			name:        "running job",
+			objects:     []client.Object{newJob(""), newPod(corev1.PodRunning, resultMessage)},
+			wantApplied: true,
+		},
+		{
+			name:          "recorded result",
+			objects:       []client.Object{newJob(batchv1.JobComplete), newPod(corev1.PodSucceeded, resultMessage)},
+			result:        recorded,
+			wantResult:    recorded,
+			wantApplied:   true,
+			wantSucceeded: true,
+		},
+		{
+			name:    "deleted job",
+			result:  recorded,
+			applied: true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			b := &fluentpvcv1alpha1.FluentPVCBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace},
+			}
+			b.Status.FinalizerJobResult = c.result.DeepCopy()
+			if c.applied {
+				b.SetConditionFinalizerJobApplied("FinalizerJobFound", "applied")
+			}
+			objects := append([]client.Object{b}, c.objects...)
+			cli := newTestClient(t, objects...)
+			r := &fluentPVCBindingReconciler{Client: cli, APIReader: cli}
+			ctx := context.Background()
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(b), b); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.updateConditionByFinalizerJobStatus(ctx, b, &fluentpvcv1alpha1.FluentPVC{}); err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+
+			got := &fluentpvcv1alpha1.FluentPVCBinding{}
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(b), got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Status.FinalizerJobResult, c.wantResult) {
+				t.Errorf("expected the result=%+v, but got %+v", c.wantResult, got.Status.FinalizerJobResult)
+			}
+			if got.IsConditionFinalizerJobApplied() != c.wantApplied {
+				t.Errorf("expected FinalizerJobApplied=%t, but got %+v", c.wantApplied, got.Status.Conditions)
+			}
+			if got.IsConditionFinalizerJobSucceeded() != c.wantSucceeded {
+				t.Errorf("expected FinalizerJobSucceeded=%t, but got %+v", c.wantSucceeded, got.Status.Conditions)
+			}
+			if got.IsConditionFinalizerJobFailed() != c.wantFailed {
+				t.Errorf("expected FinalizerJobFailed=%t, but got %+v", c.wantFailed, got.Status.Conditions)
+			}
+		})
+	}
+}
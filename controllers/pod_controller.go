@@ -9,6 +9,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -63,7 +64,7 @@ func (r *podReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
 
-	if !fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected {
+	if !pointer.BoolDeref(fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected, true) {
 		logger.Info(fmt.Sprintf(
 			"Skip processing because deletePodIfSidecarContainerTerminationDetected=false in fluentpvc='%s' for pod='%s'",
 			fpvc.Name, pod.Name,
@@ -72,6 +73,11 @@ func (r *podReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	}
 
 	containerName := fpvc.Spec.SidecarContainerTemplate.Name
+	if !hasContainer(pod, containerName) {
+		// NOTE: The pod is admitted without the sidecar container. See FluentPVCAdmissionFailurePolicyIgnore.
+		logger.Info(fmt.Sprintf("Skip processing because pod='%s' is degraded without container='%s'.", pod.Name, containerName))
+		return ctrl.Result{}, nil
+	}
 	status := findContainerStatusByName(&pod.Status, containerName)
 	if status == nil {
 		return ctrl.Result{}, xerrors.New(fmt.Sprintf("Container='%s' does not have any status.", containerName))
@@ -10,8 +10,11 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -20,24 +23,34 @@ import (
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
 	"github.com/st-tech/fluent-pvc-operator/constants"
-	podutils "github.com/st-tech/fluent-pvc-operator/utils/pod"
+	hashutils "github.com/st-tech/fluent-pvc-operator/utils/hash"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+	revisionutils "github.com/st-tech/fluent-pvc-operator/utils/revision"
+	snapshotutils "github.com/st-tech/fluent-pvc-operator/utils/snapshot"
 )
 
 //+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs,verbs=get;list;watch
-//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings,verbs=get;list;watch
-//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings/status,verbs=get
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings/status,verbs=get;update
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcrevisions,verbs=get;list;watch;create;update
 //+kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="snapshot.storage.k8s.io",resources=volumesnapshots,verbs=get;list;create;delete
 
 type pvcReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
 }
 
 func NewPVCReconciler(mgr ctrl.Manager) *pvcReconciler {
 	return &pvcReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Scheme:    mgr.GetScheme(),
 	}
 }
 
@@ -52,15 +65,30 @@ func (r *pvcReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		}
 		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
+	if owner := metav1.GetControllerOf(pvc); isOwnerPod(owner) {
+		return r.adoptEphemeralPVC(ctx, pvc, owner)
+	}
+	bindingName := pvc.Name
+	// NOTE: The finalizer pvc restored from the snapshot has the annotation of the fluentpvcbinding name.
+	//       See FluentPVCFinalizationModeSnapshot.
+	finalizerPVCOf, isFinalizerPVC := pvc.Annotations[constants.PVCAnnotationFluentPVCBindingName]
+	if isFinalizerPVC {
+		bindingName = finalizerPVCOf
+	}
 	b := &fluentpvcv1alpha1.FluentPVCBinding{}
-	if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: pvc.Name}, b); err != nil {
+	if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: bindingName}, b); err != nil {
 		if apierrors.IsNotFound(err) {
-			logger.Info(fmt.Sprintf("Requeue request because fluentpvcbinding='%s' (namespace='%s') is not found.", pvc.Name, req.Namespace))
+			logger.Info(fmt.Sprintf("Requeue request because fluentpvcbinding='%s' (namespace='%s') is not found.", bindingName, req.Namespace))
 			return requeueResult(10 * time.Second), nil
 		}
 		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 	}
-	if pvc.UID != b.Spec.PVC.UID {
+	if isFinalizerPVC && !b.IsFinalizerPVC(pvc) {
+		// NOTE: The finalizer pvc is recorded in the fluentpvcbinding status right after it is created.
+		logger.Info(fmt.Sprintf("Requeue request because pvc='%s' is not recorded as the finalizer pvc of fluentpvcbinding='%s'.", pvc.Name, b.Name))
+		return requeueResult(10 * time.Second), nil
+	}
+	if !isFinalizerPVC && pvc.UID != b.Spec.PVC.UID {
 		logger.Info(fmt.Sprintf(
 			"Skip processing because pvc.UID='%s' is different from fluentpvcbinding.Spec.PVC.UID='%s' for name='%s'.",
 			pvc.UID, b.Spec.PVC.UID, pvc.Name,
@@ -92,7 +120,8 @@ func (r *pvcReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	}
 	if b.IsConditionUnknown() {
 		logger.Info(fmt.Sprintf("fluentpvcbinding='%s' is unknown status, so skip processing.", b.Name))
-		return ctrl.Result{}, nil
+		// NOTE: Requeue to resume after the unknown status is cleared by the action annotation.
+		return requeueResult(1 * time.Minute), nil
 	}
 	if !b.IsConditionOutOfUse() {
 		logger.Info(fmt.Sprintf("fluentpvcbinding='%s' is not out of use yet.", b.Name))
@@ -115,36 +144,60 @@ func (r *pvcReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			))
 			return requeueResult(10 * time.Second), nil
 		}
-		fpvc := &fluentpvcv1alpha1.FluentPVC{}
-		if err := r.Get(ctx, client.ObjectKey{Name: metav1.GetControllerOf(b).Name}, fpvc); err != nil {
+		spec, err := r.getFinalizerFluentPVCSpec(ctx, b)
+		if err != nil {
 			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
 		}
+		snapshotMode := spec.FinalizationMode == fluentpvcv1alpha1.FluentPVCFinalizationModeSnapshot
+		if !snapshotMode {
+			message, err := r.findZoneUnavailableMessage(ctx, pvc)
+			if err != nil {
+				return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			switch {
+			case message == "":
+				if b.IsConditionZoneUnavailable() {
+					b.SetConditionNotZoneUnavailable("ZoneAvailable", fmt.Sprintf("The zone of pvc='%s' is available.", pvc.Name))
+					if err := r.Status().Update(ctx, b); err != nil {
+						return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+					}
+				}
+			case spec.ZoneUnavailablePolicy == fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicySnapshotAndRestore && b.IsBindingPVC(pvc):
+				logger.Info(fmt.Sprintf("Restore pvc='%s' into an available zone.: %s", pvc.Name, message))
+				snapshotMode = true
+			default:
+				logger.Info(fmt.Sprintf("Wait for the zone of pvc='%s' to be available.: %s", pvc.Name, message))
+				if !b.IsConditionZoneUnavailable() {
+					b.SetConditionZoneUnavailable("NoSchedulableNodes", message)
+					if err := r.Status().Update(ctx, b); err != nil {
+						return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+					}
+				}
+				return requeueResult(1 * time.Minute), nil
+			}
+		}
+		if snapshotMode && b.IsBindingPVC(pvc) {
+			released, err := r.releaseToFinalizerPVC(ctx, b, pvc, spec)
+			if err != nil {
+				return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			if !released {
+				logger.Info(fmt.Sprintf("Wait for pvc='%s' to be restored into the finalizer pvc.", pvc.Name))
+				return requeueResult(10 * time.Second), nil
+			}
+			// NOTE: The finalizer job is applied for the finalizer pvc in its #Reconcile.
+			return ctrl.Result{}, nil
+		}
 
 		j := &batchv1.Job{}
-		j.SetName(b.Name)
+		j.SetName(jobutils.FinalizerJobName(b))
 		j.SetNamespace(b.Namespace)
 		if _, err := ctrl.CreateOrUpdate(ctx, r.Client, j, func() error {
-			j.Spec = *fpvc.Spec.PVCFinalizerJobSpecTemplate.DeepCopy()
-			for _, v := range fpvc.Spec.CommonVolumes {
-				podutils.InjectOrReplaceVolume(&j.Spec.Template.Spec, v.DeepCopy())
-			}
-			podutils.InjectOrReplaceVolume(&j.Spec.Template.Spec, &corev1.Volume{
-				Name: fpvc.Spec.PVCVolumeName,
-				VolumeSource: corev1.VolumeSource{
-					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: pvc.Name,
-					},
-				},
-			})
-			for _, vm := range fpvc.Spec.CommonVolumeMounts {
-				podutils.InjectOrReplaceVolumeMount(&j.Spec.Template.Spec, vm.DeepCopy())
-			}
-			podutils.InjectOrReplaceVolumeMount(&j.Spec.Template.Spec, &corev1.VolumeMount{
-				Name:      fpvc.Spec.PVCVolumeName,
-				MountPath: fpvc.Spec.PVCVolumeMountPath,
-			})
-			for _, e := range fpvc.Spec.CommonEnvs {
-				podutils.InjectOrReplaceEnv(&j.Spec.Template.Spec, e.DeepCopy())
+			j.Spec = *spec.PVCFinalizerJobSpecTemplate.DeepCopy()
+			jobutils.InjectFluentPVC(&j.Spec.Template.Spec, spec, pvc.Name)
+			if b.Status.LostNodeName != "" {
+				// NOTE: The lost node is usually tainted by Kubernetes, but the job template may tolerate the taints.
+				excludeNode(&j.Spec.Template.Spec, b.Status.LostNodeName)
 			}
 			return ctrl.SetControllerReference(b, j, r.Scheme)
 		}); err != nil {
@@ -160,10 +213,25 @@ func (r *pvcReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	}
 
 	if b.IsConditionFinalizerJobFailed() {
-		logger.Info(fmt.Sprintf("Skip processing because the finalizer job='%s' is failed.", b.Name))
+		logger.Info(fmt.Sprintf("Skip processing because the finalizer job='%s' is failed.", jobutils.FinalizerJobName(b)))
 		return requeueResult(10 * time.Second), nil
 	}
 
+	spec, err := r.getFinalizerFluentPVCSpec(ctx, b)
+	if err != nil {
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if spec.SnapshotBeforeDelete != nil {
+		archived, err := r.archivePVC(ctx, b, pvc, spec.SnapshotBeforeDelete)
+		if err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if !archived {
+			logger.Info(fmt.Sprintf("Wait for the volumesnapshot of pvc='%s' to be ready to use.", pvc.Name))
+			return requeueResult(10 * time.Second), nil
+		}
+	}
+
 	logger.Info(fmt.Sprintf("Remove the finalizer='%s' from pvc='%s'", constants.PVCFinalizerName, pvc.Name))
 	controllerutil.RemoveFinalizer(pvc, constants.PVCFinalizerName)
 	if err := r.Update(ctx, pvc); client.IgnoreNotFound(err) != nil {
@@ -183,6 +251,302 @@ func (r *pvcReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	return ctrl.Result{}, nil
 }
 
+// getFinalizerFluentPVCSpec returns the spec of the FluentPVCRevision that the pvc is written with, so that
+// the pvc is finalized with the same tooling. The spec of the live FluentPVC is returned for
+// fluentpvcbindings that do not have any revisions or whose revision is already deleted.
+func (r *pvcReconciler) getFinalizerFluentPVCSpec(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding) (*fluentpvcv1alpha1.FluentPVCSpec, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("pvcReconciler").WithName("getFinalizerFluentPVCSpec")
+	if b.Spec.FluentPVCRevision != nil {
+		rev := &fluentpvcv1alpha1.FluentPVCRevision{}
+		if err := r.Get(ctx, client.ObjectKey{Name: b.Spec.FluentPVCRevision.Name}, rev); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+		} else if rev.UID == b.Spec.FluentPVCRevision.UID {
+			return &rev.Spec, nil
+		}
+		logger.Info(fmt.Sprintf(
+			"fluentpvcrevision='%s' of fluentpvcbinding='%s' is not found, so use the current spec of fluentpvc='%s'.",
+			b.Spec.FluentPVCRevision.Name, b.Name, b.Spec.FluentPVC.Name,
+		))
+	}
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := r.Get(ctx, client.ObjectKey{Name: metav1.GetControllerOf(b).Name}, fpvc); err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return &fpvc.Spec, nil
+}
+
+// findZoneUnavailableMessage returns the reason why the pvc cannot be finalized in its zone, or the empty string if
+// the pv of the pvc is not zonal or any schedulable nodes are in its zone.
+func (r *pvcReconciler) findZoneUnavailableMessage(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (string, error) {
+	if pvc.Spec.VolumeName == "" {
+		return "", nil
+	}
+	pv := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, client.ObjectKey{Name: pvc.Spec.VolumeName}, pv); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	zones := findVolumeZones(pv)
+	if len(zones) == 0 {
+		return "", nil
+	}
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if isNodeSchedulable(node) && matchNodeSelectorTerms(node, pv.Spec.NodeAffinity.Required.NodeSelectorTerms) {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf(
+		"No schedulable nodes are found in the zones %v of pv='%s' bound to pvc='%s'.",
+		zones, pv.Name, pvc.Name,
+	), nil
+}
+
+// releaseToFinalizerPVC takes the VolumeSnapshot of the pvc, restores it into the finalizer pvc, records it in
+// the fluentpvcbinding, and then releases the pvc. It returns true if the pvc is released.
+// See FluentPVCFinalizationModeSnapshot.
+func (r *pvcReconciler) releaseToFinalizerPVC(
+	ctx context.Context,
+	b *fluentpvcv1alpha1.FluentPVCBinding,
+	pvc *corev1.PersistentVolumeClaim,
+	spec *fluentpvcv1alpha1.FluentPVCSpec,
+) (bool, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("pvcReconciler").WithName("releaseToFinalizerPVC")
+	if spec.SnapshotFinalization == nil {
+		return false, xerrors.New(fmt.Sprintf("snapshotFinalization is not specified for fluentpvcbinding='%s'.", b.Name))
+	}
+
+	vs := snapshotutils.NewVolumeSnapshot()
+	if err := r.APIReader.Get(ctx, client.ObjectKey{Namespace: pvc.Namespace, Name: pvc.Name}, vs); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		vs = snapshotutils.NewVolumeSnapshot()
+		vs.SetName(pvc.Name)
+		vs.SetNamespace(pvc.Namespace)
+		vs.SetLabels(map[string]string{
+			constants.VolumeSnapshotLabelFluentPVCName: hashutils.LimitName(b.Spec.FluentPVC.Name),
+		})
+		if err := snapshotutils.SetSource(vs, pvc.Name, spec.SnapshotFinalization.VolumeSnapshotClassName); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		// NOTE: The VolumeSnapshot is deleted with the fluentpvcbinding after the finalizer pvc is finalized.
+		if err := ctrl.SetControllerReference(b, vs, r.Scheme); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Create volumesnapshot='%s' of pvc='%s' for fluentpvcbinding='%s'.", vs.GetName(), pvc.Name, b.Name))
+		if err := r.Create(ctx, vs); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		return false, nil
+	}
+	if snapshotutils.GetSourcePVCName(vs) != pvc.Name || !metav1.IsControlledBy(vs, b) {
+		return false, xerrors.New(fmt.Sprintf("volumesnapshot='%s' exists, but it is not taken for fluentpvcbinding='%s'.", vs.GetName(), b.Name))
+	}
+	if !snapshotutils.IsReadyToUse(vs) {
+		if message := snapshotutils.GetErrorMessage(vs); message != "" {
+			logger.Info(fmt.Sprintf("volumesnapshot='%s' of pvc='%s' has an error: %s", vs.GetName(), pvc.Name, message))
+		}
+		return false, nil
+	}
+
+	finalizerPVC := &corev1.PersistentVolumeClaim{}
+	finalizerPVCName := hashutils.LimitName(fmt.Sprintf("%s-finalizer", pvc.Name))
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pvc.Namespace, Name: finalizerPVCName}, finalizerPVC); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		finalizerPVC.SetName(finalizerPVCName)
+		finalizerPVC.SetNamespace(pvc.Namespace)
+		// NOTE: The name of the fluentpvcbinding can be longer than the label value limit, so it is an annotation.
+		finalizerPVC.SetAnnotations(map[string]string{constants.PVCAnnotationFluentPVCBindingName: b.Name})
+		finalizerPVC.Spec = corev1.PersistentVolumeClaimSpec{
+			AccessModes:      pvc.Spec.AccessModes,
+			Resources:        pvc.Spec.Resources,
+			StorageClassName: pvc.Spec.StorageClassName,
+			VolumeMode:       pvc.Spec.VolumeMode,
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: pointer.StringPtr(snapshotutils.VolumeSnapshotGVK.Group),
+				Kind:     snapshotutils.VolumeSnapshotGVK.Kind,
+				Name:     vs.GetName(),
+			},
+		}
+		if spec.SnapshotFinalization.StorageClassName != nil {
+			finalizerPVC.Spec.StorageClassName = spec.SnapshotFinalization.StorageClassName
+		}
+		controllerutil.AddFinalizer(finalizerPVC, constants.PVCFinalizerName)
+		logger.Info(fmt.Sprintf("Create the finalizer pvc='%s' from volumesnapshot='%s' for fluentpvcbinding='%s'.", finalizerPVCName, vs.GetName(), b.Name))
+		if err := r.Create(ctx, finalizerPVC); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if finalizerPVC.Annotations[constants.PVCAnnotationFluentPVCBindingName] != b.Name {
+		return false, xerrors.New(fmt.Sprintf("pvc='%s' exists, but it is not the finalizer pvc of fluentpvcbinding='%s'.", finalizerPVCName, b.Name))
+	}
+	if !b.IsFinalizerPVC(finalizerPVC) {
+		b.SetFinalizerPVC(finalizerPVC)
+		if err := r.Status().Update(ctx, b); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("Release pvc='%s' because it is restored into the finalizer pvc='%s'.", pvc.Name, finalizerPVC.Name))
+	controllerutil.RemoveFinalizer(pvc, constants.PVCFinalizerName)
+	if err := r.Update(ctx, pvc); client.IgnoreNotFound(err) != nil {
+		return false, xerrors.Errorf("Failed to remove finalizer from PVC='%s'.: %w", pvc.Name, err)
+	}
+	if err := r.Delete(ctx, pvc, deleteOptionsBackground(&pvc.UID, &pvc.ResourceVersion)); client.IgnoreNotFound(err) != nil {
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return true, nil
+}
+
+// archivePVC creates the VolumeSnapshot of the pvc to retain it after the pvc is deleted, and returns true if
+// the VolumeSnapshot is ready to use. The VolumeSnapshot has the same name as the pvc.
+func (r *pvcReconciler) archivePVC(
+	ctx context.Context,
+	b *fluentpvcv1alpha1.FluentPVCBinding,
+	pvc *corev1.PersistentVolumeClaim,
+	s *fluentpvcv1alpha1.FluentPVCSnapshotBeforeDelete,
+) (bool, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("pvcReconciler").WithName("archivePVC")
+	vs := snapshotutils.NewVolumeSnapshot()
+	// NOTE: Read VolumeSnapshots from the API server directly not to start the informer, because the CRDs may
+	//       not be installed in the cluster.
+	if err := r.APIReader.Get(ctx, client.ObjectKey{Namespace: pvc.Namespace, Name: pvc.Name}, vs); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		retentionDays := s.RetentionDays
+		if retentionDays <= 0 {
+			retentionDays = 7
+		}
+		vs = snapshotutils.NewVolumeSnapshot()
+		vs.SetName(pvc.Name)
+		vs.SetNamespace(pvc.Namespace)
+		vs.SetLabels(map[string]string{
+			constants.VolumeSnapshotLabelFluentPVCName:        hashutils.LimitName(b.Spec.FluentPVC.Name),
+			constants.VolumeSnapshotLabelFluentPVCBindingName: hashutils.LimitName(b.Name),
+		})
+		vs.SetAnnotations(map[string]string{
+			constants.VolumeSnapshotAnnotationRetainUntil: time.Now().AddDate(0, 0, int(retentionDays)).UTC().Format(time.RFC3339),
+		})
+		if err := snapshotutils.SetSource(vs, pvc.Name, s.VolumeSnapshotClassName); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Create volumesnapshot='%s' of pvc='%s' for fluentpvcbinding='%s'.", vs.GetName(), pvc.Name, b.Name))
+		if err := r.Create(ctx, vs); err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		return false, nil
+	}
+	if snapshotutils.GetSourcePVCName(vs) != pvc.Name || vs.GetLabels()[constants.VolumeSnapshotLabelFluentPVCBindingName] != hashutils.LimitName(b.Name) {
+		return false, xerrors.New(fmt.Sprintf("volumesnapshot='%s' exists, but it is not the archive of pvc='%s'.", vs.GetName(), pvc.Name))
+	}
+	if message := snapshotutils.GetErrorMessage(vs); message != "" {
+		logger.Info(fmt.Sprintf("volumesnapshot='%s' of pvc='%s' has an error: %s", vs.GetName(), pvc.Name, message))
+	}
+	return snapshotutils.IsReadyToUse(vs), nil
+}
+
+// adoptEphemeralPVC takes over the PVC created by Kubernetes for a generic ephemeral volume of the pod.
+// See FluentPVCProvisioningModeEphemeral.
+func (r *pvcReconciler) adoptEphemeralPVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, owner *metav1.OwnerReference) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("pvcReconciler").WithName("adoptEphemeralPVC")
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pvc.Namespace, Name: owner.Name}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if pod.UID != owner.UID {
+		return ctrl.Result{}, nil
+	}
+	fpvcName, ok := pod.Labels[constants.PodLabelFluentPVCName]
+	if !ok {
+		// not target
+		return ctrl.Result{}, nil
+	}
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := r.Get(ctx, client.ObjectKey{Name: fpvcName}, fpvc); err != nil {
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if fpvc.Spec.ProvisioningMode != fluentpvcv1alpha1.FluentPVCProvisioningModeEphemeral {
+		return ctrl.Result{}, nil
+	}
+	// NOTE: Kubernetes names the PVC for a generic ephemeral volume '<pod name>-<volume name>'.
+	if pvc.Name != fmt.Sprintf("%s-%s", pod.Name, fpvc.Spec.PVCVolumeName) {
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName) {
+		logger.Info(fmt.Sprintf("Add the finalizer='%s' to pvc='%s' for pod='%s'.", constants.PVCFinalizerName, pvc.Name, pod.Name))
+		controllerutil.AddFinalizer(pvc, constants.PVCFinalizerName)
+		if err := r.Update(ctx, pvc); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		// NOTE: Wait until next #Reconcile to avoid update confliction.
+		return ctrl.Result{}, nil
+	}
+
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pvc.Namespace, Name: pvc.Name}, b); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		rev, err := revisionutils.GetOrCreateFluentPVCRevision(ctx, r.Client, r.Scheme, fpvc)
+		if err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Create fluentpvcbinding='%s'(namespace='%s') for pod='%s'.", pvc.Name, pvc.Namespace, pod.Name))
+		b.SetName(pvc.Name)
+		b.SetNamespace(pvc.Namespace)
+		b.SetFluentPVC(fpvc)
+		b.SetFluentPVCRevision(rev)
+		b.SetPod(pod)
+		b.SetPVC(pvc)
+		controllerutil.AddFinalizer(b, constants.FluentPVCBindingFinalizerName)
+		if err := ctrl.SetControllerReference(fpvc, b, r.Scheme); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred: %w", err)
+		}
+		if err := r.Create(ctx, b); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		b.SetPhasePending()
+		if err := r.Status().Update(ctx, b); err != nil {
+			return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	}
+
+	// NOTE: The kubelet refuses to mount the PVC that is not owned by the pod, so keep the owner reference
+	//       until the pod is started. The owner reference must be removed before the pod is deleted because
+	//       the finalizer job cannot mount the pvc if it is terminating.
+	if pod.Status.Phase == corev1.PodPending && pod.DeletionTimestamp.IsZero() {
+		logger.Info(fmt.Sprintf("Requeue request because pod='%s' is not started yet.", pod.Name))
+		return requeueResult(10 * time.Second), nil
+	}
+	logger.Info(fmt.Sprintf("Remove the owner reference to pod='%s' from pvc='%s'.", pod.Name, pvc.Name))
+	ownerReferences := []metav1.OwnerReference{}
+	for _, o := range pvc.OwnerReferences {
+		if o.UID != owner.UID {
+			ownerReferences = append(ownerReferences, o)
+		}
+	}
+	pvc.SetOwnerReferences(ownerReferences)
+	if err := r.Update(ctx, pvc); err != nil {
+		return ctrl.Result{}, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
 func (r *pvcReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	pred := predicate.Funcs{
 		CreateFunc:  func(event.CreateEvent) bool { return true },
@@ -201,8 +565,227 @@ func (r *pvcReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// 	return xerrors.Errorf("Unexpected error occurred.: %w", err)
 	// }
 
+	collector := &orphanedPVCCollector{
+		client:      mgr.GetClient(),
+		listLimit:   300,              // TODO: make it configurable.
+		tick:        1 * time.Minute,  // TODO: make it configurable.
+		gracePeriod: 10 * time.Minute, // TODO: make it configurable.
+	}
+	if err := mgr.Add(collector); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	snapshotCollector := &expiredVolumeSnapshotCollector{
+		client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		listLimit: 300,             // TODO: make it configurable.
+		tick:      1 * time.Minute, // TODO: make it configurable.
+	}
+	if err := mgr.Add(snapshotCollector); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		WithEventFilter(pred).
 		For(&corev1.PersistentVolumeClaim{}).
 		Complete(r)
 }
+
+// orphanedPVCCollector deletes PVCs that have the finalizer of fluent-pvc-operator but no fluentpvcbinding.
+// Such PVCs are left when the pod admission fails after the PVC is created.
+type orphanedPVCCollector struct {
+	client      client.Client
+	listLimit   int64
+	tick        time.Duration
+	gracePeriod time.Duration
+}
+
+func (c *orphanedPVCCollector) Start(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("orphanedPVCCollector").WithName("Start")
+	ticker := time.NewTicker(c.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// NOTE: Returning the error stops the manager, so the transient errors are retried on the next tick.
+			if err := c.collect(context.Background()); err != nil {
+				logger.Error(err, "Failed to collect, so retry on the next tick.")
+			}
+		}
+	}
+}
+
+func (c *orphanedPVCCollector) collect(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("orphanedPVCCollector").WithName("collect")
+	// NOTE: The pods are listed once per namespace in each collection, not for each pvc.
+	podsByNamespace := map[string][]corev1.Pod{}
+	token := ""
+	for {
+		pvcList := &corev1.PersistentVolumeClaimList{}
+		if err := c.client.List(ctx, pvcList, &client.ListOptions{
+			Limit:    c.listLimit,
+			Continue: token,
+		}); err != nil {
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+
+		for i := range pvcList.Items {
+			pvc := &pvcList.Items[i]
+			if !controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName) {
+				continue
+			}
+			// NOTE: The fluentpvcbinding is created after the pvc, so wait for a while.
+			if !isCreatedBefore(pvc, c.gracePeriod) {
+				continue
+			}
+			pods, ok := podsByNamespace[pvc.Namespace]
+			if !ok {
+				podList := &corev1.PodList{}
+				if err := c.client.List(ctx, podList, client.InNamespace(pvc.Namespace)); err != nil {
+					return xerrors.Errorf("Unexpected error occurred.: %w", err)
+				}
+				pods = podList.Items
+				podsByNamespace[pvc.Namespace] = pods
+			}
+			orphaned, err := c.isOrphaned(ctx, pvc, pods)
+			if err != nil {
+				return xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			if !orphaned {
+				continue
+			}
+			logger.Info(fmt.Sprintf(
+				"Remove the finalizer='%s' from pvc='%s'(namespace='%s') because any fluentpvcbinding is not found.",
+				constants.PVCFinalizerName, pvc.Name, pvc.Namespace,
+			))
+			controllerutil.RemoveFinalizer(pvc, constants.PVCFinalizerName)
+			if err := c.client.Update(ctx, pvc); client.IgnoreNotFound(err) != nil {
+				if apierrors.IsConflict(err) {
+					// NOTE: Retry at the next tick.
+					continue
+				}
+				return xerrors.Errorf("Failed to remove finalizer from PVC='%s'.: %w", pvc.Name, err)
+			}
+			logger.Info(fmt.Sprintf("Delete pvc='%s'(namespace='%s') because it is orphaned.", pvc.Name, pvc.Namespace))
+			if err := c.client.Delete(ctx, pvc, deleteOptionsBackground(&pvc.UID, &pvc.ResourceVersion)); client.IgnoreNotFound(err) != nil {
+				return xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+		}
+
+		token = pvcList.ListMeta.Continue
+		if len(token) == 0 {
+			return nil
+		}
+	}
+}
+
+// isOrphaned returns true if the pvc is neither the pvc nor the finalizer pvc of any fluentpvcbinding, and no pods
+// in the namespace use it.
+func (c *orphanedPVCCollector) isOrphaned(ctx context.Context, pvc *corev1.PersistentVolumeClaim, pods []corev1.Pod) (bool, error) {
+	bindingName := pvc.Name
+	if name, ok := pvc.Annotations[constants.PVCAnnotationFluentPVCBindingName]; ok {
+		bindingName = name
+	}
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: pvc.Namespace, Name: bindingName}, b); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+	} else if b.IsBindingPVC(pvc) || b.IsFinalizerPVC(pvc) {
+		return false, nil
+	}
+	// NOTE: Do not delete the pvc while some pods use it even if the fluentpvcbinding is not found.
+	owner := metav1.GetControllerOf(pvc)
+	for _, pod := range pods {
+		if isOwnerPod(owner) && pod.UID == owner.UID {
+			return false, nil
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == pvc.Name {
+				return false, nil
+			}
+			// NOTE: Kubernetes names the PVC for a generic ephemeral volume '<pod name>-<volume name>'.
+			if v.Ephemeral != nil && fmt.Sprintf("%s-%s", pod.Name, v.Name) == pvc.Name {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// expiredVolumeSnapshotCollector deletes the VolumeSnapshots archived by pvcReconciler after the retention.
+// See FluentPVCSnapshotBeforeDelete.
+type expiredVolumeSnapshotCollector struct {
+	client    client.Client
+	apiReader client.Reader
+	listLimit int64
+	tick      time.Duration
+}
+
+func (c *expiredVolumeSnapshotCollector) Start(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("expiredVolumeSnapshotCollector").WithName("Start")
+	ticker := time.NewTicker(c.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// NOTE: Returning the error stops the manager, so the transient errors are retried on the next tick.
+			if err := c.collect(context.Background()); err != nil {
+				logger.Error(err, "Failed to collect, so retry on the next tick.")
+			}
+		}
+	}
+}
+
+func (c *expiredVolumeSnapshotCollector) collect(ctx context.Context) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("expiredVolumeSnapshotCollector").WithName("collect")
+	selector, err := labels.Parse(constants.VolumeSnapshotLabelFluentPVCBindingName)
+	if err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	token := ""
+	for {
+		vsList := snapshotutils.NewVolumeSnapshotList()
+		if err := c.apiReader.List(ctx, vsList, &client.ListOptions{
+			LabelSelector: selector,
+			Limit:         c.listLimit,
+			Continue:      token,
+		}); err != nil {
+			if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+				// NOTE: The CRDs of VolumeSnapshots are not installed.
+				return nil
+			}
+			return xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+
+		for i := range vsList.Items {
+			vs := &vsList.Items[i]
+			retainUntil, ok := vs.GetAnnotations()[constants.VolumeSnapshotAnnotationRetainUntil]
+			if !ok || !vs.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, retainUntil)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("Ignore volumesnapshot='%s'(namespace='%s') because the annotation='%s' is invalid.", vs.GetName(), vs.GetNamespace(), constants.VolumeSnapshotAnnotationRetainUntil))
+				continue
+			}
+			if time.Now().Before(t) {
+				continue
+			}
+			logger.Info(fmt.Sprintf("Delete volumesnapshot='%s'(namespace='%s') because it is retained until %s.", vs.GetName(), vs.GetNamespace(), retainUntil))
+			uid := vs.GetUID()
+			resourceVersion := vs.GetResourceVersion()
+			if err := c.client.Delete(ctx, vs, deleteOptionsBackground(&uid, &resourceVersion)); client.IgnoreNotFound(err) != nil {
+				return xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+		}
+
+		token = vsList.GetContinue()
+		if len(token) == 0 {
+			return nil
+		}
+	}
+}
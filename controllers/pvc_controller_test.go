@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+	snapshotutils "github.com/st-tech/fluent-pvc-operator/utils/snapshot"
+)
+
+func TestOrphanedPVCCollectorCollect(t *testing.T) {
+	const namespace = "default"
+	newPVC := func(name string, uid types.UID, annotations map[string]string, owners ...metav1.OwnerReference) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				UID:             uid,
+				Annotations:     annotations,
+				OwnerReferences: owners,
+				Finalizers:      []string{constants.PVCFinalizerName},
+			},
+		}
+	}
+	newPod := func(name string, uid types.UID, volumes ...corev1.Volume) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: uid},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "busybox"}},
+				Volumes:    volumes,
+			},
+		}
+	}
+	cases := []struct {
+		name        string
+		pvc         *corev1.PersistentVolumeClaim
+		objects     []client.Object
+		wantDeleted bool
+	}{
+		{
+			name:        "orphaned pvc",
+			pvc:         newPVC("pvc", "pvc-uid", nil),
+			wantDeleted: true,
+		},
+		{
+			name: "pvc of the fluentpvcbinding",
+			pvc:  newPVC("binding", "pvc-uid", nil),
+			objects: []client.Object{
+				&fluentpvcv1alpha1.FluentPVCBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace},
+					Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+						PVC: fluentpvcv1alpha1.ObjectIdentity{Name: "binding", UID: "pvc-uid"},
+					},
+				},
+			},
+		},
+		{
+			name: "finalizer pvc of the fluentpvcbinding",
+			pvc:  newPVC("finalizer-pvc", "finalizer-pvc-uid", map[string]string{constants.PVCAnnotationFluentPVCBindingName: "binding"}),
+			objects: []client.Object{
+				&fluentpvcv1alpha1.FluentPVCBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace},
+					Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+						PVC: fluentpvcv1alpha1.ObjectIdentity{Name: "binding", UID: "pvc-uid"},
+					},
+					Status: fluentpvcv1alpha1.FluentPVCBindingStatus{
+						FinalizerPVC: &fluentpvcv1alpha1.ObjectIdentity{Name: "finalizer-pvc", UID: "finalizer-pvc-uid"},
+					},
+				},
+			},
+		},
+		{
+			name: "pvc of another fluentpvcbinding with the same name",
+			pvc:  newPVC("binding", "pvc-uid", nil),
+			objects: []client.Object{
+				&fluentpvcv1alpha1.FluentPVCBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace},
+					Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+						PVC: fluentpvcv1alpha1.ObjectIdentity{Name: "binding", UID: "another-pvc-uid"},
+					},
+				},
+			},
+			wantDeleted: true,
+		},
+		{
+			name: "pvc used by a persistentVolumeClaim volume",
+			pvc:  newPVC("pvc", "pvc-uid", nil),
+			objects: []client.Object{
+				newPod("pod", "pod-uid", corev1.Volume{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc"},
+					},
+				}),
+			},
+		},
+		{
+			name: "pvc used by a generic ephemeral volume",
+			pvc:  newPVC("pod-data", "pvc-uid", nil),
+			objects: []client.Object{
+				newPod("pod", "pod-uid", corev1.Volume{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						Ephemeral: &corev1.EphemeralVolumeSource{},
+					},
+				}),
+			},
+		},
+		{
+			name: "pvc owned by an existing pod",
+			pvc: newPVC("pvc", "pvc-uid", nil, metav1.OwnerReference{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       "pod",
+				UID:        "pod-uid",
+				Controller: func(b bool) *bool { return &b }(true),
+			}),
+			objects: []client.Object{newPod("pod", "pod-uid")},
+		},
+		{
+			name: "pvc owned by a deleted pod",
+			pvc: newPVC("pvc", "pvc-uid", nil, metav1.OwnerReference{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       "pod",
+				UID:        "deleted-pod-uid",
+				Controller: func(b bool) *bool { return &b }(true),
+			}),
+			objects:     []client.Object{newPod("pod", "pod-uid")},
+			wantDeleted: true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			objects := append([]client.Object{c.pvc}, c.objects...)
+			collector := &orphanedPVCCollector{
+				client:    newTestClient(t, objects...),
+				listLimit: 300,
+			}
+			ctx := context.Background()
+			if err := collector.collect(ctx); err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+			err := collector.client.Get(ctx, client.ObjectKeyFromObject(c.pvc), &corev1.PersistentVolumeClaim{})
+			if c.wantDeleted {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected pvc='%s' to be deleted, but got %+v", c.pvc.Name, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected pvc='%s' to be kept, but got %+v", c.pvc.Name, err)
+			}
+		})
+	}
+}
+
+func TestPVCReconcilerZoneUnavailable(t *testing.T) {
+	const (
+		namespace = "default"
+		zoneKey   = "topology.kubernetes.io/zone"
+	)
+	newFluentPVC := func(policy fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicy) *fluentpvcv1alpha1.FluentPVC {
+		return &fluentpvcv1alpha1.FluentPVC{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc", UID: "fpvc-uid"},
+			Spec: fluentpvcv1alpha1.FluentPVCSpec{
+				PVCFinalizerJobSpecTemplate: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers:    []corev1.Container{{Name: "finalizer", Image: "busybox"}},
+							RestartPolicy: corev1.RestartPolicyNever,
+						},
+					},
+				},
+				PVCVolumeName:         "fluent-pvc",
+				PVCVolumeMountPath:    "/mnt/fluent-pvc",
+				FinalizationMode:      fluentpvcv1alpha1.FluentPVCFinalizationModeDirect,
+				ZoneUnavailablePolicy: policy,
+				SnapshotFinalization: &fluentpvcv1alpha1.FluentPVCSnapshotFinalization{
+					VolumeSnapshotClassName: "csi-snapclass",
+				},
+			},
+		}
+	}
+	newBinding := func(fpvc *fluentpvcv1alpha1.FluentPVC, zoneUnavailable bool, finalizerPVC *fluentpvcv1alpha1.ObjectIdentity) *fluentpvcv1alpha1.FluentPVCBinding {
+		b := &fluentpvcv1alpha1.FluentPVCBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "binding",
+				Namespace:       namespace,
+				UID:             "binding-uid",
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(fpvc, fluentpvcv1alpha1.GroupVersion.WithKind("FluentPVC"))},
+			},
+			Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+				FluentPVC: fluentpvcv1alpha1.ObjectIdentity{Name: fpvc.Name, UID: fpvc.UID},
+				PVC:       fluentpvcv1alpha1.ObjectIdentity{Name: "binding", UID: "pvc-uid"},
+			},
+			Status: fluentpvcv1alpha1.FluentPVCBindingStatus{FinalizerPVC: finalizerPVC},
+		}
+		b.SetConditionOutOfUse("OutOfUse", "")
+		if zoneUnavailable {
+			b.SetConditionZoneUnavailable("NoSchedulableNodes", "")
+		}
+		return b
+	}
+	newPVC := func(name string, uid types.UID, annotations map[string]string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				UID:         uid,
+				Annotations: annotations,
+				Finalizers:  []string{constants.PVCFinalizerName},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "pv"},
+		}
+	}
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+		Spec: corev1.PersistentVolumeSpec{
+			NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      zoneKey,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-a"},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	newNode := func(name, zone string, ready bool) *corev1.Node {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{zoneKey: zone}},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			},
+		}
+	}
+	finalizerPVCIdentity := &fluentpvcv1alpha1.ObjectIdentity{Name: "binding-finalizer", UID: "finalizer-pvc-uid"}
+	cases := []struct {
+		name                string
+		fpvc                *fluentpvcv1alpha1.FluentPVC
+		binding             *fluentpvcv1alpha1.FluentPVCBinding
+		pvc                 *corev1.PersistentVolumeClaim
+		nodes               []client.Object
+		wantResult          ctrl.Result
+		wantJob             bool
+		wantVolumeSnapshot  bool
+		wantZoneUnavailable bool
+	}{
+		{
+			name:  "zone available",
+			fpvc:  newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicyWait),
+			pvc:   newPVC("binding", "pvc-uid", nil),
+			nodes: []client.Object{newNode("node-a", "zone-a", true)},
+			// NOTE: The finalizer job is not finished yet, so the pvc is requeued.
+			wantResult: requeueResult(10 * time.Second),
+			wantJob:    true,
+		},
+		{
+			name:       "zone available again",
+			fpvc:       newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicyWait),
+			binding:    newBinding(newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicyWait), true, nil),
+			pvc:        newPVC("binding", "pvc-uid", nil),
+			nodes:      []client.Object{newNode("node-a", "zone-a", true)},
+			wantResult: requeueResult(10 * time.Second),
+			wantJob:    true,
+		},
+		{
+			name:                "zone unavailable with Wait",
+			fpvc:                newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicyWait),
+			pvc:                 newPVC("binding", "pvc-uid", nil),
+			nodes:               []client.Object{newNode("node-a", "zone-a", false), newNode("node-b", "zone-b", true)},
+			wantResult:          requeueResult(1 * time.Minute),
+			wantZoneUnavailable: true,
+		},
+		{
+			name:                "zone without nodes with Wait",
+			fpvc:                newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicyWait),
+			pvc:                 newPVC("binding", "pvc-uid", nil),
+			nodes:               []client.Object{newNode("node-b", "zone-b", true)},
+			wantResult:          requeueResult(1 * time.Minute),
+			wantZoneUnavailable: true,
+		},
+		{
+			name:               "zone unavailable with SnapshotAndRestore",
+			fpvc:               newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicySnapshotAndRestore),
+			pvc:                newPVC("binding", "pvc-uid", nil),
+			nodes:              []client.Object{newNode("node-a", "zone-a", false), newNode("node-b", "zone-b", true)},
+			wantResult:         requeueResult(10 * time.Second),
+			wantVolumeSnapshot: true,
+		},
+		{
+			name: "zone of the finalizer pvc unavailable with SnapshotAndRestore",
+			fpvc: newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicySnapshotAndRestore),
+			binding: newBinding(
+				newFluentPVC(fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicySnapshotAndRestore), false, finalizerPVCIdentity,
+			),
+			pvc: newPVC(
+				finalizerPVCIdentity.Name, finalizerPVCIdentity.UID,
+				map[string]string{constants.PVCAnnotationFluentPVCBindingName: "binding"},
+			),
+			nodes:               []client.Object{newNode("node-a", "zone-a", false), newNode("node-b", "zone-b", true)},
+			wantResult:          requeueResult(1 * time.Minute),
+			wantZoneUnavailable: true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			b := c.binding
+			if b == nil {
+				b = newBinding(c.fpvc, false, nil)
+			}
+			objects := append([]client.Object{c.fpvc, b, c.pvc, pv.DeepCopy()}, c.nodes...)
+			cli := newTestClient(t, objects...)
+			r := &pvcReconciler{Client: cli, APIReader: cli, Scheme: newTestScheme(t)}
+			ctx := context.Background()
+			result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(c.pvc)})
+			if err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+			if result != c.wantResult {
+				t.Errorf("expected %+v, but got %+v", c.wantResult, result)
+			}
+
+			j := &batchv1.Job{}
+			err = cli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: jobutils.FinalizerJobName(b)}, j)
+			if c.wantJob && err != nil {
+				t.Errorf("expected the finalizer job to be created, but got %+v", err)
+			}
+			if !c.wantJob && !apierrors.IsNotFound(err) {
+				t.Errorf("expected no finalizer jobs, but got %+v", err)
+			}
+
+			vs := snapshotutils.NewVolumeSnapshot()
+			err = cli.Get(ctx, client.ObjectKeyFromObject(c.pvc), vs)
+			if c.wantVolumeSnapshot {
+				if err != nil {
+					t.Errorf("expected the volumesnapshot to be created, but got %+v", err)
+				} else if name := snapshotutils.GetSourcePVCName(vs); name != c.pvc.Name {
+					t.Errorf("expected the source pvc='%s', but got '%s'", c.pvc.Name, name)
+				}
+			} else if !apierrors.IsNotFound(err) {
+				t.Errorf("expected no volumesnapshots, but got %+v", err)
+			}
+
+			updated := &fluentpvcv1alpha1.FluentPVCBinding{}
+			if err := cli.Get(ctx, client.ObjectKeyFromObject(b), updated); err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+			if got := updated.IsConditionZoneUnavailable(); got != c.wantZoneUnavailable {
+				t.Errorf("expected ZoneUnavailable=%t, but got %t", c.wantZoneUnavailable, got)
+			}
+		})
+	}
+}
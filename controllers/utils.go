@@ -1,12 +1,22 @@
 package controllers
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
+	"golang.org/x/xerrors"
+
+	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -53,12 +63,27 @@ func indexFluentPVCBindingByOwnerFluentPVC(obj client.Object) []string {
 	return []string{owner.Name}
 }
 
+func indexFluentPVCRevisionByOwnerFluentPVC(obj client.Object) []string {
+	r := obj.(*fluentpvcv1alpha1.FluentPVCRevision)
+	owner := metav1.GetControllerOf(r)
+	if !isOwnerFluentPVC(owner) {
+		return nil
+	}
+	return []string{owner.Name}
+}
+
 func isOwnerFluentPVC(owner *metav1.OwnerReference) bool {
 	return owner != nil &&
 		owner.APIVersion == fluentpvcv1alpha1.GroupVersion.String() &&
 		owner.Kind == "FluentPVC"
 }
 
+func isOwnerPod(owner *metav1.OwnerReference) bool {
+	return owner != nil &&
+		owner.APIVersion == corev1.SchemeGroupVersion.String() &&
+		owner.Kind == "Pod"
+}
+
 func requeueResult(requeueAfter time.Duration) ctrl.Result {
 	return ctrl.Result{
 		Requeue:      true,
@@ -66,29 +91,19 @@ func requeueResult(requeueAfter time.Duration) ctrl.Result {
 	}
 }
 
-func getFinishedStatus(j *batchv1.Job) (bool, batchv1.JobConditionType) {
-	for _, c := range j.Status.Conditions {
-		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
-			return true, c.Type
-		}
-	}
-	return false, ""
-}
-
-func isJobSucceeded(j *batchv1.Job) bool {
-	isFinished, t := getFinishedStatus(j)
-	return isFinished && t == batchv1.JobComplete
-}
-
-func isJobFailed(j *batchv1.Job) bool {
-	isFinished, t := getFinishedStatus(j)
-	return isFinished && t == batchv1.JobFailed
-}
-
 func isPodRunningPhase(pod *corev1.Pod) bool {
 	return pod.Status.Phase == corev1.PodRunning
 }
 
+func hasContainer(pod *corev1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func findContainerStatusByName(status *corev1.PodStatus, name string) *corev1.ContainerStatus {
 	for _, c := range status.ContainerStatuses {
 		if c.Name == name {
@@ -103,3 +118,249 @@ func isCreatedBefore(obj client.Object, duration time.Duration) bool {
 	creationTimestamp := obj.GetCreationTimestamp()
 	return creationTimestamp.Before(&threshold)
 }
+
+// isBindingPodWaitedBefore returns true if the binding pod has been waited for since before the duration.
+// The pod is waited for since the fluentpvcbinding is created or the pvc is reattached to another pod.
+func isBindingPodWaitedBefore(b *fluentpvcv1alpha1.FluentPVCBinding, duration time.Duration) bool {
+	c := meta.FindStatusCondition(b.Status.Conditions, string(fluentpvcv1alpha1.FluentPVCBindingConditionReady))
+	if c == nil || c.Status == metav1.ConditionTrue {
+		return isCreatedBefore(b, duration)
+	}
+	threshold := metav1.NewTime(time.Now().Add(-duration))
+	return c.LastTransitionTime.Before(&threshold)
+}
+
+func isTransitionedBefore(t metav1.Time, duration time.Duration) bool {
+	threshold := metav1.NewTime(time.Now().Add(-duration))
+	return t.Before(&threshold)
+}
+
+func indexPodByNodeName(obj client.Object) []string {
+	pod := obj.(*corev1.Pod)
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+func indexPodByFluentPVCName(obj client.Object) []string {
+	name, ok := obj.GetLabels()[constants.PodLabelFluentPVCName]
+	if !ok {
+		return nil
+	}
+	return []string{name}
+}
+
+func findNodeCondition(node *corev1.Node, t corev1.NodeConditionType) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == t {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// excludeNode adds the required node affinity not to schedule the pod on the node.
+func excludeNode(spec *corev1.PodSpec, nodeName string) {
+	if spec.Affinity == nil {
+		spec.Affinity = &corev1.Affinity{}
+	}
+	if spec.Affinity.NodeAffinity == nil {
+		spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	selector := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	// NOTE: NodeSelectorTerms are ORed, so the requirement is added to all the terms.
+	for i := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[i].MatchFields = append(selector.NodeSelectorTerms[i].MatchFields, corev1.NodeSelectorRequirement{
+			Key:      "metadata.name",
+			Operator: corev1.NodeSelectorOpNotIn,
+			Values:   []string{nodeName},
+		})
+	}
+}
+
+// nodeFailureGracePeriod returns the grace period before the pod on the NotReady node is regarded as gone.
+func nodeFailureGracePeriod(fpvc *fluentpvcv1alpha1.FluentPVC) time.Duration {
+	seconds := pointer.Int64Deref(fpvc.Spec.NodeFailureHandling.GracePeriodSeconds, 300)
+	return time.Duration(seconds) * time.Second
+}
+
+// isNodeSchedulable returns true if new pods can be scheduled on the node.
+func isNodeSchedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	cond := findNodeCondition(node, corev1.NodeReady)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+// isZoneTopologyKey returns true if the key is the topology key of zones, such as "topology.kubernetes.io/zone"
+// and the ones of CSI drivers like "topology.gke.io/zone".
+func isZoneTopologyKey(key string) bool {
+	return key == corev1.LabelFailureDomainBetaZone || strings.HasSuffix(key, "/zone")
+}
+
+// findVolumeZones returns the zones which the pv is restricted to by its node affinity.
+func findVolumeZones(pv *corev1.PersistentVolume) []string {
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return nil
+	}
+	zones := []string{}
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		for _, req := range term.MatchExpressions {
+			if isZoneTopologyKey(req.Key) && req.Operator == corev1.NodeSelectorOpIn {
+				zones = append(zones, req.Values...)
+			}
+		}
+	}
+	return zones
+}
+
+// matchNodeSelectorTerms returns true if the node matches any of the terms.
+func matchNodeSelectorTerms(node *corev1.Node, terms []corev1.NodeSelectorTerm) bool {
+	for _, term := range terms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			// NOTE: An empty term matches no objects.
+			continue
+		}
+		if matchNodeSelectorRequirements(node.Labels, term.MatchExpressions) &&
+			matchNodeSelectorRequirements(fields.Set{"metadata.name": node.Name}, term.MatchFields) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNodeSelectorRequirements(set map[string]string, reqs []corev1.NodeSelectorRequirement) bool {
+	operators := map[corev1.NodeSelectorOperator]selection.Operator{
+		corev1.NodeSelectorOpIn:           selection.In,
+		corev1.NodeSelectorOpNotIn:        selection.NotIn,
+		corev1.NodeSelectorOpExists:       selection.Exists,
+		corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+		corev1.NodeSelectorOpGt:           selection.GreaterThan,
+		corev1.NodeSelectorOpLt:           selection.LessThan,
+	}
+	for _, req := range reqs {
+		op, ok := operators[req.Operator]
+		if !ok {
+			return false
+		}
+		r, err := labels.NewRequirement(req.Key, op, req.Values)
+		if err != nil {
+			return false
+		}
+		if !r.Matches(labels.Set(set)) {
+			return false
+		}
+	}
+	return true
+}
+
+func workloadKey(kind string, obj client.Object) string {
+	return fmt.Sprintf("%s/%s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
+
+func podTemplateOf(w client.Object) *corev1.PodTemplateSpec {
+	switch w := w.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template
+	}
+	return nil
+}
+
+// isWorkloadRolledOut returns true if all the pods of the workload are updated to the latest pod template.
+func isWorkloadRolledOut(w client.Object) bool {
+	switch w := w.(type) {
+	case *appsv1.Deployment:
+		replicas := int32(1)
+		if w.Spec.Replicas != nil {
+			replicas = *w.Spec.Replicas
+		}
+		return w.Status.ObservedGeneration >= w.Generation &&
+			w.Status.UpdatedReplicas == replicas &&
+			w.Status.Replicas == replicas &&
+			w.Status.AvailableReplicas == replicas
+	case *appsv1.StatefulSet:
+		if w.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			// NOTE: The pods are never updated until they are deleted.
+			return true
+		}
+		return w.Status.ObservedGeneration >= w.Generation &&
+			w.Status.UpdateRevision == w.Status.CurrentRevision
+	case *appsv1.DaemonSet:
+		if w.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+			// NOTE: The pods are never updated until they are deleted.
+			return true
+		}
+		return w.Status.ObservedGeneration >= w.Generation &&
+			w.Status.UpdatedNumberScheduled == w.Status.DesiredNumberScheduled &&
+			w.Status.NumberUnavailable == 0
+	}
+	return true
+}
+
+// hasWorkloadRolloutCapacity returns true if the update strategy of the workload can replace more pods now.
+// false is returned if the strategy never replaces the pods by itself, or the unavailable pods of the workload
+// already reach maxUnavailable of the strategy.
+func hasWorkloadRolloutCapacity(w client.Object) (bool, error) {
+	switch w := w.(type) {
+	case *appsv1.Deployment:
+		if w.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+			return true, nil
+		}
+		replicas := int32(1)
+		if w.Spec.Replicas != nil {
+			replicas = *w.Spec.Replicas
+		}
+		maxUnavailable := intstr.FromString("25%")
+		if ru := w.Spec.Strategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+			maxUnavailable = *ru.MaxUnavailable
+		}
+		limit, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, int(replicas), false)
+		if err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		unavailable := int(w.Status.UnavailableReplicas)
+		// NOTE: maxUnavailable can be 0 when maxSurge is not 0, so the rollout is allowed if no pods are unavailable.
+		return unavailable == 0 || unavailable < limit, nil
+	case *appsv1.StatefulSet:
+		if w.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			return false, nil
+		}
+		replicas := int32(1)
+		if w.Spec.Replicas != nil {
+			replicas = *w.Spec.Replicas
+		}
+		if ru := w.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition >= replicas {
+			// NOTE: No pods are updated because all the ordinals are less than the partition.
+			return false, nil
+		}
+		// NOTE: The rolling update of StatefulSets replaces the pods one by one.
+		return w.Status.ReadyReplicas >= replicas, nil
+	case *appsv1.DaemonSet:
+		if w.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+			return false, nil
+		}
+		maxUnavailable := intstr.FromInt(1)
+		if ru := w.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+			maxUnavailable = *ru.MaxUnavailable
+		}
+		limit, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, int(w.Status.DesiredNumberScheduled), true)
+		if err != nil {
+			return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		unavailable := int(w.Status.NumberUnavailable)
+		return unavailable == 0 || unavailable < limit, nil
+	}
+	return false, nil
+}
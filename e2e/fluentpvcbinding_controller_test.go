@@ -0,0 +1,83 @@
+package e2e
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+)
+
+var _ = Describe("fluentpvcbinding_controller", func() {
+	var tc *TestK8SClient
+	var id string
+
+	BeforeEach(func() {
+		tc = NewTestK8SClient(k8sClient)
+		id = RandomString()
+		ns := &corev1.Namespace{}
+		ns.SetName(id)
+		tc.FindOrCreate(ctx, ns)
+	})
+	AfterEach(func() {
+		tc.DeleteAllInNamespace(ctx, id, &corev1.Pod{})
+		tc.DeleteFluentPVC(ctx, id)
+		tc.DeleteNamespace(ctx, id)
+	})
+	Context("The provisioning mode is Controller", func() {
+		When("a pod is applied", func() {
+			It("should create the PVC and the FluentPVCBinding for the pod", func() {
+				By("preparing objects on k8s")
+				fpvc := TestDefaultFluentPVC.DeepCopy()
+				fpvc.SetName(id)
+				fpvc.Spec.ProvisioningMode = fluentpvcv1alpha1.FluentPVCProvisioningModeController
+				tc.FindOrCreate(ctx, fpvc)
+
+				pod := TestDefaultPod.DeepCopy()
+				pod.SetName(id)
+				pod.SetNamespace(id)
+				pod.SetLabels(map[string]string{constants.PodLabelFluentPVCName: id})
+				tc.FindOrCreate(ctx, pod)
+
+				By("expecting injecting the PVC named after the FluentPVCBinding")
+				pod = &corev1.Pod{}
+				pod.SetName(id)
+				pod.SetNamespace(id)
+				tc.Find(ctx, pod)
+				name, ok := pod.Labels[constants.PodLabelFluentPVCBindingName]
+				Expect(ok).Should(BeTrue())
+				Expect(pod.Spec.Volumes).Should(ContainElement(Satisfy(func(v corev1.Volume) bool {
+					return v.Name == fpvc.Spec.PVCVolumeName &&
+						v.PersistentVolumeClaim != nil &&
+						v.PersistentVolumeClaim.ClaimName == name
+				})))
+
+				By("expecting the FluentPVCBinding is created by the controller")
+				EventuallyPodRunning(tc, ctx, id, id).Should(Succeed())
+				EventuallyFluentPVCBindingSatisfied(tc, ctx, id, name, func(b *fluentpvcv1alpha1.FluentPVCBinding) bool {
+					return b.IsConditionReady() &&
+						b.IsBindingPod(pod) &&
+						b.Spec.PVC.Name == name &&
+						b.Spec.FluentPVC.Name == id &&
+						b.Spec.FluentPVCRevision != nil
+				}).Should(Succeed())
+				pvc := &corev1.PersistentVolumeClaim{}
+				pvc.SetName(name)
+				pvc.SetNamespace(id)
+				tc.Find(ctx, pvc)
+				Expect(controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName)).Should(BeTrue())
+
+				By("expecting the FluentPVCBinding is finalized after the pod is deleted")
+				Eventually(func() error {
+					return tc.Delete(ctx, pod, client.GracePeriodSeconds(0))
+				}, 10).Should(Succeed())
+				EventuallyPodDeleted(tc, ctx, id, id).Should(Succeed())
+				EventuallyFluentPVCBindingDeleted(tc, ctx, id, name).Should(Succeed())
+			})
+		})
+	})
+})
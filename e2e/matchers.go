@@ -8,6 +8,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
 )
 
 var (
@@ -61,3 +63,34 @@ func EventuallyPodDeleted(c client.Client, ctx context.Context, namespace, name
 		return xerrors.New("Pod is running.")
 	}, defaultEventuallyTimeoutSeconds)
 }
+
+func EventuallyFluentPVCBindingSatisfied(c client.Client, ctx context.Context, namespace, name string, f func(b *fluentpvcv1alpha1.FluentPVCBinding) bool) AsyncAssertion {
+	return Eventually(func() error {
+		b := &fluentpvcv1alpha1.FluentPVCBinding{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, b); err != nil {
+			return err
+		}
+		if !f(b) {
+			return xerrors.New("FluentPVCBinding is not satisfied.: " + string(b.Status.Phase))
+		}
+		return nil
+	}, defaultEventuallyTimeoutSeconds)
+}
+
+func EventuallyFluentPVCBindingDeleted(c client.Client, ctx context.Context, namespace, name string) AsyncAssertion {
+	return Eventually(func() error {
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &fluentpvcv1alpha1.FluentPVCBinding{}); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		return xerrors.New("FluentPVCBinding is found.")
+	}, defaultEventuallyTimeoutSeconds)
+}
+
+func EventuallyPVCDeleted(c client.Client, ctx context.Context, namespace, name string) AsyncAssertion {
+	return Eventually(func() error {
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &corev1.PersistentVolumeClaim{}); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		return xerrors.New("PVC is found.")
+	}, defaultEventuallyTimeoutSeconds)
+}
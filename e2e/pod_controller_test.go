@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
@@ -135,7 +136,7 @@ var _ = Describe("pod_controller", func() {
 				It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 					arg := NewAssertBehaviorArg()
 					arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerEcho.DeepCopy()
-					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 					arg.pod.Spec.RestartPolicy = corev1.RestartPolicyNever
 					arg.assertConsistencyRunning = true
 					AssertBehavior(arg)
@@ -152,7 +153,7 @@ var _ = Describe("pod_controller", func() {
 				It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 					arg := NewAssertBehaviorArg()
 					arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerExit1.DeepCopy()
-					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 					arg.pod.Spec.RestartPolicy = corev1.RestartPolicyNever
 					arg.assertConsistencyRunning = true
 					AssertBehavior(arg)
@@ -170,7 +171,7 @@ var _ = Describe("pod_controller", func() {
 				It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 					arg := NewAssertBehaviorArg()
 					arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerEcho.DeepCopy()
-					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 					arg.pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
 					arg.assertRestarting = true
 					arg.assertConsistencyRunning = true
@@ -189,7 +190,7 @@ var _ = Describe("pod_controller", func() {
 				It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 					arg := NewAssertBehaviorArg()
 					arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerExit1.DeepCopy()
-					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+					arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 					arg.pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
 					arg.assertRestarting = true
 					arg.assertConsistencyRunning = true
@@ -209,7 +210,7 @@ var _ = Describe("pod_controller", func() {
 					It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 						arg := NewAssertBehaviorArg()
 						arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerEcho.DeepCopy()
-						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 						arg.pod.Spec.RestartPolicy = corev1.RestartPolicyNever
 						arg.assertConsistencyRunning = true
 						arg.deleteFluentPVCAfterPodApplied = true
@@ -228,7 +229,7 @@ var _ = Describe("pod_controller", func() {
 					It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 						arg := NewAssertBehaviorArg()
 						arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerExit1.DeepCopy()
-						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 						arg.pod.Spec.RestartPolicy = corev1.RestartPolicyNever
 						arg.assertConsistencyRunning = true
 						arg.deleteFluentPVCAfterPodApplied = true
@@ -248,7 +249,7 @@ var _ = Describe("pod_controller", func() {
 					It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 						arg := NewAssertBehaviorArg()
 						arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerEcho.DeepCopy()
-						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 						arg.pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
 						arg.assertRestarting = true
 						arg.assertConsistencyRunning = true
@@ -269,7 +270,7 @@ var _ = Describe("pod_controller", func() {
 					It("should not delete the pod if DeletePodIfSidecarContainerTerminationDetected = false", func() {
 						arg := NewAssertBehaviorArg()
 						arg.fpvc.Spec.SidecarContainerTemplate = *TestSidecarContainerExit1.DeepCopy()
-						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = false
+						arg.fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(false)
 						arg.pod.Spec.RestartPolicy = corev1.RestartPolicyAlways
 						arg.assertRestarting = true
 						arg.assertConsistencyRunning = true
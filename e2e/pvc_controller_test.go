@@ -0,0 +1,104 @@
+package e2e
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/xerrors"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
+)
+
+var _ = Describe("pvc_controller", func() {
+	var tc *TestK8SClient
+	var id string
+
+	BeforeEach(func() {
+		tc = NewTestK8SClient(k8sClient)
+		id = RandomString()
+		ns := &corev1.Namespace{}
+		ns.SetName(id)
+		tc.FindOrCreate(ctx, ns)
+	})
+	AfterEach(func() {
+		tc.DeleteAllInNamespace(ctx, id, &corev1.Pod{})
+		tc.DeleteFluentPVC(ctx, id)
+		tc.DeleteNamespace(ctx, id)
+	})
+	Context("The provisioning mode is Ephemeral", func() {
+		When("a pod is applied and deleted", func() {
+			AssertBehavior := func(podName string) {
+				By("preparing objects on k8s")
+				fpvc := TestDefaultFluentPVC.DeepCopy()
+				fpvc.SetName(id)
+				fpvc.Spec.ProvisioningMode = fluentpvcv1alpha1.FluentPVCProvisioningModeEphemeral
+				tc.FindOrCreate(ctx, fpvc)
+
+				pod := TestDefaultPod.DeepCopy()
+				pod.SetName(podName)
+				pod.SetNamespace(id)
+				pod.SetLabels(map[string]string{constants.PodLabelFluentPVCName: id})
+				tc.FindOrCreate(ctx, pod)
+
+				By("expecting the ephemeral PVC is adopted")
+				EventuallyPodRunning(tc, ctx, id, podName).Should(Succeed())
+				pod = &corev1.Pod{}
+				pod.SetName(podName)
+				pod.SetNamespace(id)
+				tc.Find(ctx, pod)
+				// NOTE: Kubernetes names the PVC for a generic ephemeral volume '<pod name>-<volume name>'.
+				name := fmt.Sprintf("%s-%s", podName, fpvc.Spec.PVCVolumeName)
+				EventuallyFluentPVCBindingSatisfied(tc, ctx, id, name, func(b *fluentpvcv1alpha1.FluentPVCBinding) bool {
+					return b.IsConditionReady() && b.IsBindingPod(pod) && b.Spec.PVC.Name == name
+				}).Should(Succeed())
+				Eventually(func() error {
+					pvc := &corev1.PersistentVolumeClaim{}
+					if err := tc.Get(ctx, client.ObjectKey{Namespace: id, Name: name}, pvc); err != nil {
+						return err
+					}
+					if !controllerutil.ContainsFinalizer(pvc, constants.PVCFinalizerName) {
+						return xerrors.New(fmt.Sprintf("pvc='%s' does not have the finalizer.", name))
+					}
+					if metav1.IsControlledBy(pvc, pod) {
+						return xerrors.New(fmt.Sprintf("pvc='%s' is still owned by pod='%s'.", name, pod.Name))
+					}
+					return nil
+				}, defaultEventuallyTimeoutSeconds).Should(Succeed())
+
+				By("expecting the ephemeral PVC is finalized after the pod is deleted")
+				Eventually(func() error {
+					return tc.Delete(ctx, pod, client.GracePeriodSeconds(0))
+				}, 10).Should(Succeed())
+				EventuallyPodDeleted(tc, ctx, id, podName).Should(Succeed())
+				EventuallyFluentPVCBindingSatisfied(tc, ctx, id, name, func(b *fluentpvcv1alpha1.FluentPVCBinding) bool {
+					return b.IsConditionFinalizerJobApplied()
+				}).Should(Succeed())
+				j := &batchv1.Job{}
+				j.SetName(jobutils.FinalizerJobName(&fluentpvcv1alpha1.FluentPVCBinding{ObjectMeta: metav1.ObjectMeta{Name: name}}))
+				j.SetNamespace(id)
+				Expect(validation.IsDNS1123Label(j.Name)).Should(BeEmpty())
+				tc.Find(ctx, j)
+				EventuallyFluentPVCBindingDeleted(tc, ctx, id, name).Should(Succeed())
+				EventuallyPVCDeleted(tc, ctx, id, name).Should(Succeed())
+			}
+			It("should adopt the ephemeral PVC and finalize it after the pod is deleted", func() {
+				AssertBehavior(id)
+			})
+			It("should finalize the ephemeral PVC of a pod whose name is longer than a DNS_LABEL", func() {
+				// NOTE: The FluentPVCBinding is named after the PVC, so the name of the finalizer Job is limited.
+				AssertBehavior(id + "-" + strings.Repeat("a", validation.DNS1123LabelMaxLength))
+			})
+		})
+	})
+})
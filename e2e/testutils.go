@@ -90,7 +90,7 @@ var (
 			PVCVolumeMountPath:       "/mnt/test",
 			CommonEnvs:               []corev1.EnvVar{},
 			SidecarContainerTemplate: *TestSidecarContainerEcho.DeepCopy(),
-			DeletePodIfSidecarContainerTerminationDetected: true,
+			DeletePodIfSidecarContainerTerminationDetected: pointer.BoolPtr(true),
 			PVCFinalizerJobSpecTemplate: batchv1.JobSpec{
 				BackoffLimit: pointer.Int32Ptr(0),
 				Template: corev1.PodTemplateSpec{
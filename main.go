@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -44,6 +45,10 @@ func main() {
 		"The controller will load its initial configuration from this file. "+
 			"Omit this flag to use the default configuration values. "+
 			"Command-line flags override configuration from this file.")
+	var fluentPVCValidationNamespaces string
+	flag.StringVar(&fluentPVCValidationNamespaces, "fluent-pvc-validation-namespaces", "default",
+		"Comma-separated namespaces to validate FluentPVCs against. "+
+			"FluentPVCs with spec.allowedNamespaces are validated against the allowed namespaces instead.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -88,10 +93,14 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
 		os.Exit(1)
 	}
-	if err = webhooks.SetupFluentPVCWebhookWithManager(mgr); err != nil {
+	if err = webhooks.SetupFluentPVCWebhookWithManager(mgr, strings.Split(fluentPVCValidationNamespaces, ",")...); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "FluentPVC")
 		os.Exit(1)
 	}
+	if err = webhooks.SetupFluentPVCBindingWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "FluentPVCBinding")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MaxNameLength is the max length of names generated by ComputeName. Names are limited to a DNS_LABEL
+// because they are also used as label values, such as the job-name label of finalizer jobs.
+const MaxNameLength = validation.DNS1123LabelMaxLength
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// ComputeName returns a name joining the prefix and the hashes with '-'. The prefix is sanitized and
+// truncated so that the name is a valid DNS_LABEL, while the hashes are kept to make the name unique.
+func ComputeName(prefix string, hashes ...string) string {
+	suffix := ""
+	for _, h := range hashes {
+		suffix += "-" + h
+	}
+	prefix = SanitizeName(prefix)
+	if max := MaxNameLength - len(suffix); len(prefix) > max {
+		prefix = strings.TrimRight(prefix[:max], "-")
+	}
+	if prefix == "" {
+		return strings.TrimLeft(suffix, "-")
+	}
+	return prefix + suffix
+}
+
+// SanitizeName converts the name into lower case alphanumeric characters or '-', and trims '-' at both ends.
+func SanitizeName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// LimitName returns the name as it is if it is a valid DNS_LABEL. Otherwise, it returns the name computed by
+// ComputeName with the hash of the name, so that the long names are still distinguished from each other.
+func LimitName(name string) string {
+	if len(validation.IsDNS1123Label(name)) == 0 {
+		return name
+	}
+	return ComputeName(name, ComputeHash(name, nil))
+}
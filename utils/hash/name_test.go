@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestSanitizeName(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "valid name", in: "fluent-pvc-0", want: "fluent-pvc-0"},
+		{name: "upper case", in: "Fluent-PVC", want: "fluent-pvc"},
+		{name: "invalid characters", in: "fluent_pvc.name", want: "fluent-pvc-name"},
+		{name: "consecutive invalid characters", in: "fluent__.pvc", want: "fluent-pvc"},
+		{name: "leading and trailing hyphens", in: "-fluent-pvc-", want: "fluent-pvc"},
+		{name: "only invalid characters", in: "_.", want: ""},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if got := SanitizeName(c.in); got != c.want {
+				t.Errorf("expected '%s', but got '%s'", c.want, got)
+			}
+		})
+	}
+}
+
+func TestComputeName(t *testing.T) {
+	cases := []struct {
+		name   string
+		prefix string
+		hashes []string
+		want   string
+	}{
+		{name: "short prefix", prefix: "fluent-pvc", hashes: []string{"abc", "def"}, want: "fluent-pvc-abc-def"},
+		{name: "sanitized prefix", prefix: "Fluent_PVC", hashes: []string{"abc"}, want: "fluent-pvc-abc"},
+		{name: "empty prefix", prefix: "", hashes: []string{"abc", "def"}, want: "abc-def"},
+		{name: "no hashes", prefix: "fluent-pvc", want: "fluent-pvc"},
+		{
+			name:   "long prefix",
+			prefix: strings.Repeat("a", 100),
+			hashes: []string{"abc", "def"},
+			want:   strings.Repeat("a", MaxNameLength-len("-abc-def")) + "-abc-def",
+		},
+		{
+			name:   "long prefix truncated at a hyphen",
+			prefix: strings.Repeat("a", MaxNameLength-len("-abc")-1) + "-" + strings.Repeat("b", 10),
+			hashes: []string{"abc"},
+			want:   strings.Repeat("a", MaxNameLength-len("-abc")-1) + "-abc",
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := ComputeName(c.prefix, c.hashes...)
+			if got != c.want {
+				t.Errorf("expected '%s', but got '%s'", c.want, got)
+			}
+			if errs := validation.IsDNS1123Label(got); len(errs) != 0 {
+				t.Errorf("expected a DNS_LABEL, but got '%s': %v", got, errs)
+			}
+		})
+	}
+}
+
+func TestLimitName(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "valid name", in: "fluent-pvc-binding", want: "fluent-pvc-binding"},
+		{name: "name of max length", in: strings.Repeat("a", MaxNameLength), want: strings.Repeat("a", MaxNameLength)},
+		{name: "long name", in: long, want: ComputeName(long, ComputeHash(long, nil))},
+		{name: "invalid name", in: "Fluent_PVC", want: ComputeName("Fluent_PVC", ComputeHash("Fluent_PVC", nil))},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := LimitName(c.in)
+			if got != c.want {
+				t.Errorf("expected '%s', but got '%s'", c.want, got)
+			}
+			if errs := validation.IsDNS1123Label(got); len(errs) != 0 {
+				t.Errorf("expected a DNS_LABEL, but got '%s': %v", got, errs)
+			}
+		})
+	}
+
+	t.Run("long names sharing the prefix", func(t *testing.T) {
+		a := LimitName(long + "-a")
+		b := LimitName(long + "-b")
+		if a == b {
+			t.Errorf("expected different names, but got '%s' for both", a)
+		}
+	})
+}
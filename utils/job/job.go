@@ -0,0 +1,107 @@
+package job
+
+import (
+	"encoding/json"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	hashutils "github.com/st-tech/fluent-pvc-operator/utils/hash"
+	podutils "github.com/st-tech/fluent-pvc-operator/utils/pod"
+)
+
+// InjectFluentPVC injects the PVC of the claim name, the common volumes, volumeMounts and envs of the FluentPVC
+// into the pod spec of the finalizer job.
+// NOTE: targetContainers of the FluentPVC selects the containers of the pods using the PVC, so it is not applied
+// to the finalizer job. Otherwise the job could succeed without the PVC mounted and the PVC would be deleted.
+func InjectFluentPVC(podSpec *corev1.PodSpec, spec *fluentpvcv1alpha1.FluentPVCSpec, claimName string) {
+	injector := podutils.NewInjector(podSpec, podutils.MergeStrategies{})
+	if spec.InjectInitContainers {
+		injector.WithInitContainers()
+	}
+	for _, v := range spec.CommonVolumes {
+		injector.InjectVolume(v.DeepCopy())
+	}
+	injector.InjectVolume(&corev1.Volume{
+		Name: spec.PVCVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			},
+		},
+	})
+	for _, vm := range spec.CommonVolumeMounts {
+		injector.InjectVolumeMount(vm.DeepCopy())
+	}
+	injector.InjectVolumeMountWithSubPaths(&corev1.VolumeMount{
+		Name:      spec.PVCVolumeName,
+		MountPath: spec.PVCVolumeMountPath,
+	}, spec.PVCVolumeMountSubPaths)
+	for _, e := range spec.CommonEnvs {
+		injector.InjectEnv(e.DeepCopy())
+	}
+}
+
+// FinalizerJobName returns the name of the finalizer job of the fluentpvcbinding. The name of the fluentpvcbinding
+// can be longer than a DNS_LABEL (e.g. the PVC name of a generic ephemeral volume), but the job name must be
+// a DNS_LABEL because it is also the value of the job-name label of the job pods.
+func FinalizerJobName(b *fluentpvcv1alpha1.FluentPVCBinding) string {
+	return hashutils.LimitName(b.Name)
+}
+
+// GetFinishedStatus returns true and the condition type if the job is completed or failed.
+func GetFinishedStatus(j *batchv1.Job) (bool, batchv1.JobConditionType) {
+	for _, c := range j.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c.Type
+		}
+	}
+	return false, ""
+}
+
+func IsSucceeded(j *batchv1.Job) bool {
+	isFinished, t := GetFinishedStatus(j)
+	return isFinished && t == batchv1.JobComplete
+}
+
+func IsFailed(j *batchv1.Job) bool {
+	isFinished, t := GetFinishedStatus(j)
+	return isFinished && t == batchv1.JobFailed
+}
+
+// FindResult returns the result reported as the JSON termination message by the terminated containers of the
+// pods, or nil if no containers report it. The messages that are not JSON objects are ignored. The results of the
+// succeeded pods are preferred to the others, and then the latest result is preferred so that the last attempt
+// wins among the retried pods.
+func FindResult(pods []corev1.Pod) *fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult {
+	var found *fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult
+	foundSucceeded := false
+	for _, pod := range pods {
+		succeeded := pod.Status.Phase == corev1.PodSucceeded
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Terminated == nil {
+				continue
+			}
+			message := strings.TrimSpace(cs.State.Terminated.Message)
+			if !strings.HasPrefix(message, "{") {
+				continue
+			}
+			result := &fluentpvcv1alpha1.FluentPVCBindingFinalizerJobResult{}
+			if err := json.Unmarshal([]byte(message), result); err != nil {
+				continue
+			}
+			result.PodName = pod.Name
+			result.ContainerName = cs.Name
+			result.FinishedAt = cs.State.Terminated.FinishedAt
+			if found == nil ||
+				(succeeded && !foundSucceeded) ||
+				(succeeded == foundSucceeded && found.FinishedAt.Before(&result.FinishedAt)) {
+				found = result
+				foundSucceeded = succeeded
+			}
+		}
+	}
+	return found
+}
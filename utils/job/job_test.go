@@ -0,0 +1,134 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindResult(t *testing.T) {
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	newPod := func(name string, phase corev1.PodPhase, message string, finishedAt time.Time) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name: "finalizer",
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{
+								Message:    message,
+								FinishedAt: metav1.NewTime(finishedAt),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	cases := []struct {
+		name            string
+		pods            []corev1.Pod
+		wantNil         bool
+		wantPodName     string
+		wantDestination string
+	}{
+		{
+			name:    "no pods",
+			pods:    nil,
+			wantNil: true,
+		},
+		{
+			name: "running container",
+			pods: []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-0"},
+					Status: corev1.PodStatus{
+						Phase: corev1.PodRunning,
+						ContainerStatuses: []corev1.ContainerStatus{
+							{Name: "finalizer", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+						},
+					},
+				},
+			},
+			wantNil: true,
+		},
+		{
+			name: "message not starting with a brace",
+			pods: []corev1.Pod{
+				newPod("pod-0", corev1.PodSucceeded, `shipped: {"destinationURI": "s3://bucket/a"}`, now),
+			},
+			wantNil: true,
+		},
+		{
+			name: "invalid JSON",
+			pods: []corev1.Pod{
+				newPod("pod-0", corev1.PodSucceeded, `{"destinationURI": `, now),
+			},
+			wantNil: true,
+		},
+		{
+			name: "JSON with surrounding spaces",
+			pods: []corev1.Pod{
+				newPod("pod-0", corev1.PodSucceeded, "\n  {\"destinationURI\": \"s3://bucket/a\"}  \n", now),
+			},
+			wantPodName:     "pod-0",
+			wantDestination: "s3://bucket/a",
+		},
+		{
+			name: "succeeded pod over later failed pod",
+			pods: []corev1.Pod{
+				newPod("pod-failed", corev1.PodFailed, `{"destinationURI": "s3://bucket/failed"}`, now.Add(time.Minute)),
+				newPod("pod-succeeded", corev1.PodSucceeded, `{"destinationURI": "s3://bucket/succeeded"}`, now),
+			},
+			wantPodName:     "pod-succeeded",
+			wantDestination: "s3://bucket/succeeded",
+		},
+		{
+			name: "latest attempt among retried failed pods",
+			pods: []corev1.Pod{
+				newPod("pod-1", corev1.PodFailed, `{"destinationURI": "s3://bucket/1"}`, now.Add(time.Minute)),
+				newPod("pod-2", corev1.PodFailed, `{"destinationURI": "s3://bucket/2"}`, now.Add(2*time.Minute)),
+				newPod("pod-0", corev1.PodFailed, `{"destinationURI": "s3://bucket/0"}`, now),
+			},
+			wantPodName:     "pod-2",
+			wantDestination: "s3://bucket/2",
+		},
+		{
+			name: "latest attempt ignoring pods without results",
+			pods: []corev1.Pod{
+				newPod("pod-0", corev1.PodFailed, `{"destinationURI": "s3://bucket/0"}`, now),
+				newPod("pod-1", corev1.PodFailed, "OOMKilled", now.Add(time.Minute)),
+			},
+			wantPodName:     "pod-0",
+			wantDestination: "s3://bucket/0",
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			result := FindResult(c.pods)
+			if c.wantNil {
+				if result != nil {
+					t.Fatalf("expected no result, but got %+v", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("expected a result, but got nil")
+			}
+			if result.PodName != c.wantPodName {
+				t.Errorf("expected pod name '%s', but got '%s'", c.wantPodName, result.PodName)
+			}
+			if result.ContainerName != "finalizer" {
+				t.Errorf("expected container name 'finalizer', but got '%s'", result.ContainerName)
+			}
+			if result.DestinationURI != c.wantDestination {
+				t.Errorf("expected destination URI '%s', but got '%s'", c.wantDestination, result.DestinationURI)
+			}
+		})
+	}
+}
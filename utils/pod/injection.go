@@ -1,84 +1,402 @@
 package pod
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func InjectOrReplaceContainer(podSpec *corev1.PodSpec, container *corev1.Container) {
-	containers := []corev1.Container{}
-	containerFound := false
-	for _, c := range podSpec.Containers {
-		if c.Name == container.Name {
-			containerFound = true
-			containers = append(containers, *container.DeepCopy())
-			continue
+// MergeStrategy defines how to merge an injected item into the pod spec when an item of the same name exists.
+type MergeStrategy string
+
+const (
+	// MergeStrategyReplace replaces the existing item with the injected item.
+	MergeStrategyReplace MergeStrategy = "Replace"
+	// MergeStrategyKeepExisting keeps the existing item and discards the injected item.
+	MergeStrategyKeepExisting MergeStrategy = "KeepExisting"
+	// MergeStrategyFail keeps the existing item and reports the conflict as a failure.
+	MergeStrategyFail MergeStrategy = "Fail"
+)
+
+// MergeStrategies is a set of MergeStrategy for each field. An empty MergeStrategy means MergeStrategyReplace.
+type MergeStrategies struct {
+	Containers   MergeStrategy
+	Volumes      MergeStrategy
+	VolumeMounts MergeStrategy
+	Envs         MergeStrategy
+	PodTemplate  MergeStrategy
+}
+
+// Conflict is an injected item whose name is already used by a different item in the pod spec.
+type Conflict struct {
+	// Field of the pod: "containers", "volumes", "volumeMounts", "env", "labels", "annotations",
+	// "securityContext", "tolerations" or "resources".
+	Field string
+	// Name of the item.
+	Name string
+	// Name of the container that has the item. Empty for containers and volumes.
+	ContainerName string
+	// MergeStrategy applied to the conflict.
+	Strategy MergeStrategy
+}
+
+func (c *Conflict) String() string {
+	if c.ContainerName == "" {
+		return fmt.Sprintf("%s '%s' already exists. (strategy: %s)", c.Field, c.Name, c.Strategy)
+	}
+	return fmt.Sprintf("%s '%s' already exists in container '%s'. (strategy: %s)", c.Field, c.Name, c.ContainerName, c.Strategy)
+}
+
+// HasFailedConflict returns true if any conflicts are merged by MergeStrategyFail.
+func HasFailedConflict(conflicts []Conflict) bool {
+	for _, c := range conflicts {
+		if c.Strategy == MergeStrategyFail {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerSelector selects the containers to inject volumeMounts and envs into.
+type ContainerSelector struct {
+	names        map[string]bool
+	nameRegex    *regexp.Regexp
+	excludeNames map[string]bool
+	alwaysNames  map[string]bool
+}
+
+// NewContainerSelector returns a ContainerSelector that selects the containers whose names are in names or
+// fully match nameRegex, and are not in excludeNames. All containers are selected if both names and nameRegex
+// are empty. The containers whose names are in alwaysNames are selected regardless of the other conditions.
+func NewContainerSelector(names []string, nameRegex string, excludeNames []string, alwaysNames ...string) (*ContainerSelector, error) {
+	s := &ContainerSelector{
+		names:        toSet(names),
+		excludeNames: toSet(excludeNames),
+		alwaysNames:  toSet(alwaysNames),
+	}
+	if nameRegex != "" {
+		r, err := regexp.Compile("^(?:" + nameRegex + ")$")
+		if err != nil {
+			return nil, err
 		}
-		containers = append(containers, *c.DeepCopy())
+		s.nameRegex = r
+	}
+	return s, nil
+}
+
+func (s *ContainerSelector) Matches(name string) bool {
+	if s == nil || s.alwaysNames[name] {
+		return true
+	}
+	if s.excludeNames[name] {
+		return false
 	}
-	if !containerFound {
-		containers = append(containers, *container.DeepCopy())
+	if len(s.names) == 0 && s.nameRegex == nil {
+		return true
 	}
-	podSpec.Containers = containers
+	return s.names[name] || (s.nameRegex != nil && s.nameRegex.MatchString(name))
+}
+
+func toSet(names []string) map[string]bool {
+	set := map[string]bool{}
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
+}
+
+// Injector injects items into the pod spec according to the merge strategies, and records the conflicts.
+// Items that are equal to the existing items are not regarded as conflicts.
+type Injector struct {
+	podSpec             *corev1.PodSpec
+	strategies          MergeStrategies
+	selector            *ContainerSelector
+	initContainers      bool
+	ephemeralContainers map[string]bool
+	conflicts           []Conflict
 }
 
-func InjectOrReplaceVolume(podSpec *corev1.PodSpec, volume *corev1.Volume) {
-	volumes := []corev1.Volume{}
-	volumeFound := false
-	for _, v := range podSpec.Volumes {
-		if v.Name == volume.Name {
-			volumeFound = true
-			volumes = append(volumes, *volume.DeepCopy())
+func NewInjector(podSpec *corev1.PodSpec, strategies MergeStrategies) *Injector {
+	return &Injector{podSpec: podSpec, strategies: strategies}
+}
+
+// WithContainerSelector limits the containers to inject volumeMounts and envs into. All containers are
+// selected by default.
+func (i *Injector) WithContainerSelector(selector *ContainerSelector) *Injector {
+	i.selector = selector
+	return i
+}
+
+// WithInitContainers injects volumeMounts and envs into the init containers selected by the ContainerSelector too.
+func (i *Injector) WithInitContainers() *Injector {
+	i.initContainers = true
+	return i
+}
+
+// WithOnlyEphemeralContainers injects volumeMounts and envs only into the ephemeral containers of the names.
+// It is used for the pods/ephemeralcontainers subresource, which allows updating only ephemeral containers.
+func (i *Injector) WithOnlyEphemeralContainers(names ...string) *Injector {
+	i.ephemeralContainers = toSet(names)
+	return i
+}
+
+// Conflicts returns the conflicts recorded by the injections.
+func (i *Injector) Conflicts() []Conflict {
+	return i.conflicts
+}
+
+func (i *Injector) InjectContainer(container *corev1.Container) {
+	for idx := range i.podSpec.Containers {
+		c := &i.podSpec.Containers[idx]
+		if c.Name != container.Name {
 			continue
 		}
-		volumes = append(volumes, *v.DeepCopy())
+		if i.merge("containers", c.Name, "", i.strategies.Containers, apiequality.Semantic.DeepEqual(c, container)) {
+			i.podSpec.Containers[idx] = *container.DeepCopy()
+		}
+		return
 	}
-	if !volumeFound {
-		volumes = append(volumes, *volume.DeepCopy())
+	i.podSpec.Containers = append(i.podSpec.Containers, *container.DeepCopy())
+}
+
+func (i *Injector) InjectVolume(volume *corev1.Volume) {
+	for idx := range i.podSpec.Volumes {
+		v := &i.podSpec.Volumes[idx]
+		if v.Name != volume.Name {
+			continue
+		}
+		if i.merge("volumes", v.Name, "", i.strategies.Volumes, apiequality.Semantic.DeepEqual(v, volume)) {
+			i.podSpec.Volumes[idx] = *volume.DeepCopy()
+		}
+		return
+	}
+	i.podSpec.Volumes = append(i.podSpec.Volumes, *volume.DeepCopy())
+}
+
+// InjectVolumeMount injects the volumeMount into the selected containers.
+func (i *Injector) InjectVolumeMount(volumeMount *corev1.VolumeMount) {
+	i.InjectVolumeMountWithSubPaths(volumeMount, nil)
+}
+
+// InjectVolumeMountWithSubPaths injects the volumeMount into the selected containers with the subPath
+// for each container name. The subPath of the volumeMount is used for the containers not in subPaths.
+func (i *Injector) InjectVolumeMountWithSubPaths(volumeMount *corev1.VolumeMount, subPaths map[string]string) {
+	for _, t := range i.targets() {
+		volumeMount := volumeMount.DeepCopy()
+		if subPath, ok := subPaths[t.name]; ok {
+			volumeMount.SubPath = subPath
+		}
+		found := false
+		for idx := range *t.volumeMounts {
+			vm := &(*t.volumeMounts)[idx]
+			if vm.Name != volumeMount.Name {
+				continue
+			}
+			found = true
+			if i.merge("volumeMounts", vm.Name, t.name, i.strategies.VolumeMounts, apiequality.Semantic.DeepEqual(vm, volumeMount)) {
+				(*t.volumeMounts)[idx] = *volumeMount.DeepCopy()
+			}
+			break
+		}
+		if !found {
+			*t.volumeMounts = append(*t.volumeMounts, *volumeMount.DeepCopy())
+		}
 	}
-	podSpec.Volumes = volumes
 }
 
-func InjectOrReplaceVolumeMount(podSpec *corev1.PodSpec, volumeMount *corev1.VolumeMount) {
-	containers := []corev1.Container{}
-	for _, c := range podSpec.Containers {
-		c := *c.DeepCopy()
-		volumeMounts := []corev1.VolumeMount{}
-		volumeMountFound := false
-		for _, vm := range c.VolumeMounts {
-			if vm.Name == volumeMount.Name {
-				volumeMountFound = true
-				volumeMounts = append(volumeMounts, *volumeMount.DeepCopy())
+// InjectEnv injects the env into the selected containers.
+func (i *Injector) InjectEnv(env *corev1.EnvVar) {
+	for _, t := range i.targets() {
+		found := false
+		for idx := range *t.env {
+			e := &(*t.env)[idx]
+			if e.Name != env.Name {
 				continue
 			}
-			volumeMounts = append(volumeMounts, *vm.DeepCopy())
+			found = true
+			if i.merge("env", e.Name, t.name, i.strategies.Envs, apiequality.Semantic.DeepEqual(e, env)) {
+				(*t.env)[idx] = *env.DeepCopy()
+			}
+			break
+		}
+		if !found {
+			*t.env = append(*t.env, *env.DeepCopy())
+		}
+	}
+}
+
+// InjectLabels injects the labels into the metadata of the pod.
+func (i *Injector) InjectLabels(meta *metav1.ObjectMeta, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	if meta.Labels == nil {
+		meta.Labels = map[string]string{}
+	}
+	i.injectStringMap("labels", meta.Labels, labels)
+}
+
+// InjectAnnotations injects the annotations into the metadata of the pod.
+func (i *Injector) InjectAnnotations(meta *metav1.ObjectMeta, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	i.injectStringMap("annotations", meta.Annotations, annotations)
+}
+
+func (i *Injector) injectStringMap(field string, existing, injected map[string]string) {
+	for _, k := range sortedKeys(injected) {
+		if v, ok := existing[k]; ok && !i.merge(field, k, "", i.strategies.PodTemplate, v == injected[k]) {
+			continue
+		}
+		existing[k] = injected[k]
+	}
+}
+
+// InjectSecurityContext injects the non-zero fields of the security context into the pod.
+// Each field is merged separately, so the fields not specified in the security context are kept.
+func (i *Injector) InjectSecurityContext(securityContext *corev1.PodSecurityContext) {
+	if securityContext == nil {
+		return
+	}
+	if i.podSpec.SecurityContext == nil {
+		i.podSpec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	existing := reflect.ValueOf(i.podSpec.SecurityContext).Elem()
+	injected := reflect.ValueOf(securityContext.DeepCopy()).Elem()
+	for idx := 0; idx < injected.NumField(); idx++ {
+		f := injected.Field(idx)
+		if f.IsZero() {
+			continue
 		}
-		if !volumeMountFound {
-			volumeMounts = append(volumeMounts, *volumeMount.DeepCopy())
+		e := existing.Field(idx)
+		name := strings.Split(injected.Type().Field(idx).Tag.Get("json"), ",")[0]
+		if !e.IsZero() && !i.merge("securityContext", name, "", i.strategies.PodTemplate, apiequality.Semantic.DeepEqual(e.Interface(), f.Interface())) {
+			continue
 		}
-		c.VolumeMounts = volumeMounts
-		containers = append(containers, c)
+		e.Set(f)
 	}
-	podSpec.Containers = containers
 }
 
-func InjectOrReplaceEnv(podSpec *corev1.PodSpec, env *corev1.EnvVar) {
-	containers := []corev1.Container{}
-	for _, c := range podSpec.Containers {
-		c := *c.DeepCopy()
-		envs := []corev1.EnvVar{}
-		envFound := false
-		for _, e := range c.Env {
-			if e.Name == env.Name {
-				envFound = true
-				envs = append(envs, *env.DeepCopy())
+// InjectToleration injects the toleration into the pod. Tolerations of the same key and effect are regarded
+// as the same item.
+func (i *Injector) InjectToleration(toleration *corev1.Toleration) {
+	for idx := range i.podSpec.Tolerations {
+		t := &i.podSpec.Tolerations[idx]
+		if t.Key != toleration.Key || t.Effect != toleration.Effect {
+			continue
+		}
+		name := fmt.Sprintf("%s:%s", t.Key, t.Effect)
+		if i.merge("tolerations", name, "", i.strategies.PodTemplate, apiequality.Semantic.DeepEqual(t, toleration)) {
+			i.podSpec.Tolerations[idx] = *toleration.DeepCopy()
+		}
+		return
+	}
+	i.podSpec.Tolerations = append(i.podSpec.Tolerations, *toleration.DeepCopy())
+}
+
+// InjectResources injects the resource requests and limits into the container of the name.
+// Each resource name is merged separately. Nothing is injected if the pod does not have the container.
+func (i *Injector) InjectResources(containerName string, resources *corev1.ResourceRequirements) {
+	for idx := range i.podSpec.Containers {
+		c := &i.podSpec.Containers[idx]
+		if c.Name != containerName {
+			continue
+		}
+		if len(resources.Requests) > 0 && c.Resources.Requests == nil {
+			c.Resources.Requests = corev1.ResourceList{}
+		}
+		if len(resources.Limits) > 0 && c.Resources.Limits == nil {
+			c.Resources.Limits = corev1.ResourceList{}
+		}
+		i.injectResourceList("requests", c.Name, c.Resources.Requests, resources.Requests)
+		i.injectResourceList("limits", c.Name, c.Resources.Limits, resources.Limits)
+		return
+	}
+}
+
+func (i *Injector) injectResourceList(prefix, containerName string, existing, injected corev1.ResourceList) {
+	names := []string{}
+	for n := range injected {
+		names = append(names, string(n))
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		rn := corev1.ResourceName(n)
+		q := injected[rn]
+		if e, ok := existing[rn]; ok && !i.merge("resources", fmt.Sprintf("%s.%s", prefix, n), containerName, i.strategies.PodTemplate, e.Cmp(q) == 0) {
+			continue
+		}
+		existing[rn] = q.DeepCopy()
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// injectionTarget is a container of any types to inject volumeMounts and envs into.
+type injectionTarget struct {
+	name         string
+	env          *[]corev1.EnvVar
+	volumeMounts *[]corev1.VolumeMount
+}
+
+func (i *Injector) targets() []injectionTarget {
+	targets := []injectionTarget{}
+	if i.ephemeralContainers != nil {
+		for idx := range i.podSpec.EphemeralContainers {
+			c := &i.podSpec.EphemeralContainers[idx]
+			if i.ephemeralContainers[c.Name] {
+				targets = append(targets, injectionTarget{name: c.Name, env: &c.Env, volumeMounts: &c.VolumeMounts})
 			}
-			envs = append(envs, e)
 		}
-		if !envFound {
-			envs = append(envs, *env.DeepCopy())
+		return targets
+	}
+	for idx := range i.podSpec.Containers {
+		c := &i.podSpec.Containers[idx]
+		if i.selector.Matches(c.Name) {
+			targets = append(targets, injectionTarget{name: c.Name, env: &c.Env, volumeMounts: &c.VolumeMounts})
 		}
-		c.Env = envs
-		containers = append(containers, c)
 	}
-	podSpec.Containers = containers
+	if i.initContainers {
+		for idx := range i.podSpec.InitContainers {
+			c := &i.podSpec.InitContainers[idx]
+			if i.selector.Matches(c.Name) {
+				targets = append(targets, injectionTarget{name: c.Name, env: &c.Env, volumeMounts: &c.VolumeMounts})
+			}
+		}
+	}
+	return targets
+}
+
+// merge records the conflict if the items are not equal, and returns true if the existing item should be replaced.
+func (i *Injector) merge(field, name, containerName string, strategy MergeStrategy, equal bool) bool {
+	if equal {
+		return false
+	}
+	if strategy == "" {
+		strategy = MergeStrategyReplace
+	}
+	i.conflicts = append(i.conflicts, Conflict{
+		Field:         field,
+		Name:          name,
+		ContainerName: containerName,
+		Strategy:      strategy,
+	})
+	return strategy == MergeStrategyReplace
 }
@@ -0,0 +1,759 @@
+package pod
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInjectorMerge(t *testing.T) {
+	appEnv := corev1.EnvVar{Name: "ENV", Value: "app"}
+	injectedEnv := corev1.EnvVar{Name: "ENV", Value: "injected"}
+	appVolume := corev1.Volume{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+	injectedVolume := corev1.Volume{
+		Name: "data",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "fluent-pvc"},
+		},
+	}
+	appVolumeMount := corev1.VolumeMount{Name: "data", MountPath: "/app"}
+	injectedVolumeMount := corev1.VolumeMount{Name: "data", MountPath: "/mnt/fluent-pvc"}
+	appSidecar := corev1.Container{Name: "sidecar", Image: "app-sidecar"}
+	injectedSidecar := corev1.Container{Name: "sidecar", Image: "fluentd"}
+	newPodSpec := func(containers ...corev1.Container) corev1.PodSpec {
+		return corev1.PodSpec{Containers: containers}
+	}
+	newContainer := func(name string, env []corev1.EnvVar, volumeMounts []corev1.VolumeMount) corev1.Container {
+		return corev1.Container{Name: name, Image: "busybox", Env: env, VolumeMounts: volumeMounts}
+	}
+	newInjectedContainer := func(name string) corev1.Container {
+		return newContainer(name, []corev1.EnvVar{injectedEnv}, []corev1.VolumeMount{injectedVolumeMount})
+	}
+	newSelector := func(names []string, nameRegex string, excludeNames []string, alwaysNames ...string) *ContainerSelector {
+		s, err := NewContainerSelector(names, nameRegex, excludeNames, alwaysNames...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	// NOTE: Sidecars of service meshes like istio-proxy have their own envs and volumeMounts,
+	//       so they are usually excluded from the targets.
+	istioProxy := newContainer("istio-proxy", []corev1.EnvVar{appEnv}, []corev1.VolumeMount{appVolumeMount})
+	selectorPodSpec := newPodSpec(
+		newContainer("app", nil, nil),
+		newContainer("worker", nil, nil),
+		istioProxy,
+		newContainer("sidecar", nil, nil),
+	)
+	newEphemeralContainer := func(name string, env []corev1.EnvVar, volumeMounts []corev1.VolumeMount) corev1.EphemeralContainer {
+		return corev1.EphemeralContainer{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+				Name:         name,
+				Image:        "busybox",
+				Env:          env,
+				VolumeMounts: volumeMounts,
+			},
+		}
+	}
+	initPodSpec := corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			newContainer("init", nil, nil),
+			newContainer("istio-init", []corev1.EnvVar{appEnv}, nil),
+		},
+		Containers: []corev1.Container{newContainer("app", nil, nil)},
+	}
+	ephemeralPodSpec := corev1.PodSpec{
+		Containers: []corev1.Container{newContainer("app", nil, nil)},
+		EphemeralContainers: []corev1.EphemeralContainer{
+			newEphemeralContainer("debugger", nil, nil),
+			newEphemeralContainer("another-debugger", nil, nil),
+		},
+	}
+	int64Ptr := func(i int64) *int64 { return &i }
+	newResources := func(requests map[corev1.ResourceName]string) corev1.ResourceRequirements {
+		r := corev1.ResourceRequirements{Requests: corev1.ResourceList{}}
+		for n, q := range requests {
+			r.Requests[n] = resource.MustParse(q)
+		}
+		return r
+	}
+	appTolerations := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "app", Effect: corev1.TaintEffectNoSchedule},
+	}
+	injectedToleration := corev1.Toleration{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpEqual,
+		Value:    "logging",
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+	failStrategies := MergeStrategies{VolumeMounts: MergeStrategyFail, Envs: MergeStrategyFail}
+	injectEnvAndVolumeMount := func(i *Injector) {
+		i.InjectEnv(&injectedEnv)
+		i.InjectVolumeMount(&injectedVolumeMount)
+	}
+
+	cases := []struct {
+		name          string
+		podSpec       corev1.PodSpec
+		strategies    MergeStrategies
+		selector      *ContainerSelector
+		meta          metav1.ObjectMeta
+		inject        func(i *Injector)
+		injectMeta    func(i *Injector, meta *metav1.ObjectMeta)
+		want          corev1.PodSpec
+		wantMeta      metav1.ObjectMeta
+		wantConflicts []Conflict
+	}{
+		{
+			name:       "new items are appended",
+			podSpec:    newPodSpec(newContainer("app", nil, nil)),
+			strategies: MergeStrategies{Envs: MergeStrategyFail, VolumeMounts: MergeStrategyFail},
+			inject: func(i *Injector) {
+				i.InjectEnv(&injectedEnv)
+				i.InjectVolumeMount(&injectedVolumeMount)
+				i.InjectVolume(&injectedVolume)
+				i.InjectContainer(&injectedSidecar)
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{
+					newContainer("app", []corev1.EnvVar{injectedEnv}, []corev1.VolumeMount{injectedVolumeMount}),
+					injectedSidecar,
+				},
+				Volumes: []corev1.Volume{injectedVolume},
+			},
+		},
+		{
+			name: "equal items are not conflicts",
+			podSpec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					newContainer("app", []corev1.EnvVar{injectedEnv}, []corev1.VolumeMount{injectedVolumeMount}),
+				},
+				Volumes: []corev1.Volume{injectedVolume},
+			},
+			strategies: MergeStrategies{
+				Volumes:      MergeStrategyFail,
+				VolumeMounts: MergeStrategyFail,
+				Envs:         MergeStrategyFail,
+			},
+			inject: func(i *Injector) {
+				i.InjectEnv(&injectedEnv)
+				i.InjectVolumeMount(&injectedVolumeMount)
+				i.InjectVolume(&injectedVolume)
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{
+					newContainer("app", []corev1.EnvVar{injectedEnv}, []corev1.VolumeMount{injectedVolumeMount}),
+				},
+				Volumes: []corev1.Volume{injectedVolume},
+			},
+		},
+		{
+			name:       "equal container is not a conflict",
+			podSpec:    newPodSpec(newContainer("app", nil, nil), injectedSidecar),
+			strategies: MergeStrategies{Containers: MergeStrategyFail},
+			inject:     func(i *Injector) { i.InjectContainer(&injectedSidecar) },
+			want:       newPodSpec(newContainer("app", nil, nil), injectedSidecar),
+		},
+		{
+			name:       "env with the Replace strategy",
+			podSpec:    newPodSpec(newContainer("app", []corev1.EnvVar{appEnv}, nil)),
+			strategies: MergeStrategies{Envs: MergeStrategyReplace},
+			inject:     func(i *Injector) { i.InjectEnv(&injectedEnv) },
+			want:       newPodSpec(newContainer("app", []corev1.EnvVar{injectedEnv}, nil)),
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:    "env with the default strategy",
+			podSpec: newPodSpec(newContainer("app", []corev1.EnvVar{appEnv}, nil)),
+			inject:  func(i *Injector) { i.InjectEnv(&injectedEnv) },
+			want:    newPodSpec(newContainer("app", []corev1.EnvVar{injectedEnv}, nil)),
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:       "env with the KeepExisting strategy",
+			podSpec:    newPodSpec(newContainer("app", []corev1.EnvVar{appEnv}, nil)),
+			strategies: MergeStrategies{Envs: MergeStrategyKeepExisting},
+			inject:     func(i *Injector) { i.InjectEnv(&injectedEnv) },
+			want:       newPodSpec(newContainer("app", []corev1.EnvVar{appEnv}, nil)),
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name:       "env with the Fail strategy",
+			podSpec:    newPodSpec(newContainer("app", []corev1.EnvVar{appEnv}, nil)),
+			strategies: MergeStrategies{Envs: MergeStrategyFail},
+			inject:     func(i *Injector) { i.InjectEnv(&injectedEnv) },
+			want:       newPodSpec(newContainer("app", []corev1.EnvVar{appEnv}, nil)),
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			// NOTE: InjectOrReplaceEnv used to append the replacement and also the original env.
+			name: "env replaced without duplicates",
+			podSpec: newPodSpec(newContainer("app", []corev1.EnvVar{
+				{Name: "BEFORE", Value: "app"},
+				appEnv,
+				{Name: "AFTER", Value: "app"},
+			}, nil)),
+			strategies: MergeStrategies{Envs: MergeStrategyReplace},
+			inject: func(i *Injector) {
+				i.InjectEnv(&injectedEnv)
+				i.InjectEnv(&injectedEnv)
+			},
+			want: newPodSpec(newContainer("app", []corev1.EnvVar{
+				{Name: "BEFORE", Value: "app"},
+				injectedEnv,
+				{Name: "AFTER", Value: "app"},
+			}, nil)),
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:       "volumeMount with the Replace strategy",
+			podSpec:    newPodSpec(newContainer("app", nil, []corev1.VolumeMount{appVolumeMount})),
+			strategies: MergeStrategies{VolumeMounts: MergeStrategyReplace},
+			inject:     func(i *Injector) { i.InjectVolumeMount(&injectedVolumeMount) },
+			want:       newPodSpec(newContainer("app", nil, []corev1.VolumeMount{injectedVolumeMount})),
+			wantConflicts: []Conflict{
+				{Field: "volumeMounts", Name: "data", ContainerName: "app", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:       "volumeMount with the KeepExisting strategy",
+			podSpec:    newPodSpec(newContainer("app", nil, []corev1.VolumeMount{appVolumeMount})),
+			strategies: MergeStrategies{VolumeMounts: MergeStrategyKeepExisting},
+			inject:     func(i *Injector) { i.InjectVolumeMount(&injectedVolumeMount) },
+			want:       newPodSpec(newContainer("app", nil, []corev1.VolumeMount{appVolumeMount})),
+			wantConflicts: []Conflict{
+				{Field: "volumeMounts", Name: "data", ContainerName: "app", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name:       "volumeMount with the Fail strategy",
+			podSpec:    newPodSpec(newContainer("app", nil, []corev1.VolumeMount{appVolumeMount})),
+			strategies: MergeStrategies{VolumeMounts: MergeStrategyFail},
+			inject:     func(i *Injector) { i.InjectVolumeMount(&injectedVolumeMount) },
+			want:       newPodSpec(newContainer("app", nil, []corev1.VolumeMount{appVolumeMount})),
+			wantConflicts: []Conflict{
+				{Field: "volumeMounts", Name: "data", ContainerName: "app", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name:       "volume with the Replace strategy",
+			podSpec:    corev1.PodSpec{Volumes: []corev1.Volume{appVolume}},
+			strategies: MergeStrategies{Volumes: MergeStrategyReplace},
+			inject:     func(i *Injector) { i.InjectVolume(&injectedVolume) },
+			want:       corev1.PodSpec{Volumes: []corev1.Volume{injectedVolume}},
+			wantConflicts: []Conflict{
+				{Field: "volumes", Name: "data", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:       "volume with the KeepExisting strategy",
+			podSpec:    corev1.PodSpec{Volumes: []corev1.Volume{appVolume}},
+			strategies: MergeStrategies{Volumes: MergeStrategyKeepExisting},
+			inject:     func(i *Injector) { i.InjectVolume(&injectedVolume) },
+			want:       corev1.PodSpec{Volumes: []corev1.Volume{appVolume}},
+			wantConflicts: []Conflict{
+				{Field: "volumes", Name: "data", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name:       "container with the Replace strategy",
+			podSpec:    newPodSpec(newContainer("app", nil, nil), appSidecar),
+			strategies: MergeStrategies{Containers: MergeStrategyReplace},
+			inject:     func(i *Injector) { i.InjectContainer(&injectedSidecar) },
+			want:       newPodSpec(newContainer("app", nil, nil), injectedSidecar),
+			wantConflicts: []Conflict{
+				{Field: "containers", Name: "sidecar", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:       "container with the Fail strategy",
+			podSpec:    newPodSpec(newContainer("app", nil, nil), appSidecar),
+			strategies: MergeStrategies{Containers: MergeStrategyFail},
+			inject:     func(i *Injector) { i.InjectContainer(&injectedSidecar) },
+			want:       newPodSpec(newContainer("app", nil, nil), appSidecar),
+			wantConflicts: []Conflict{
+				{Field: "containers", Name: "sidecar", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name: "strategies of each field",
+			podSpec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					newContainer("app", []corev1.EnvVar{appEnv}, []corev1.VolumeMount{appVolumeMount}),
+				},
+				Volumes: []corev1.Volume{appVolume},
+			},
+			strategies: MergeStrategies{
+				Volumes:      MergeStrategyReplace,
+				VolumeMounts: MergeStrategyKeepExisting,
+				Envs:         MergeStrategyFail,
+			},
+			inject: func(i *Injector) {
+				i.InjectVolume(&injectedVolume)
+				i.InjectVolumeMount(&injectedVolumeMount)
+				i.InjectEnv(&injectedEnv)
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{
+					newContainer("app", []corev1.EnvVar{appEnv}, []corev1.VolumeMount{appVolumeMount}),
+				},
+				Volumes: []corev1.Volume{injectedVolume},
+			},
+			wantConflicts: []Conflict{
+				{Field: "volumes", Name: "data", Strategy: MergeStrategyReplace},
+				{Field: "volumeMounts", Name: "data", ContainerName: "app", Strategy: MergeStrategyKeepExisting},
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name:       "containers selected by names",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector([]string{"app"}, "", nil),
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newContainer("worker", nil, nil),
+				istioProxy,
+				newContainer("sidecar", nil, nil),
+			),
+		},
+		{
+			name:       "containers selected by nameRegex",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector(nil, "app|work.*", nil),
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newInjectedContainer("worker"),
+				istioProxy,
+				newContainer("sidecar", nil, nil),
+			),
+		},
+		{
+			name:       "nameRegex fully matching the names",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector(nil, "work", nil),
+			inject:     injectEnvAndVolumeMount,
+			want:       selectorPodSpec,
+		},
+		{
+			name:       "containers selected by names or nameRegex",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector([]string{"sidecar"}, "app", nil),
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newContainer("worker", nil, nil),
+				istioProxy,
+				newInjectedContainer("sidecar"),
+			),
+		},
+		{
+			name:       "all containers without the selector",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newInjectedContainer("worker"),
+				istioProxy,
+				newInjectedContainer("sidecar"),
+			),
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "istio-proxy", Strategy: MergeStrategyFail},
+				{Field: "volumeMounts", Name: "data", ContainerName: "istio-proxy", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name:       "istio-proxy excluded",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector(nil, "", []string{"istio-proxy"}),
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newInjectedContainer("worker"),
+				istioProxy,
+				newInjectedContainer("sidecar"),
+			),
+		},
+		{
+			name:       "istio-proxy excluded even if nameRegex matches",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector(nil, ".*", []string{"istio-proxy"}),
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newInjectedContainer("worker"),
+				istioProxy,
+				newInjectedContainer("sidecar"),
+			),
+		},
+		{
+			name:       "sidecar always selected",
+			podSpec:    selectorPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector([]string{"app"}, "", []string{"sidecar"}, "sidecar"),
+			inject:     injectEnvAndVolumeMount,
+			want: newPodSpec(
+				newInjectedContainer("app"),
+				newContainer("worker", nil, nil),
+				istioProxy,
+				newInjectedContainer("sidecar"),
+			),
+		},
+		{
+			name:       "init containers not selected by default",
+			podSpec:    initPodSpec,
+			strategies: failStrategies,
+			inject:     injectEnvAndVolumeMount,
+			want: corev1.PodSpec{
+				InitContainers: initPodSpec.InitContainers,
+				Containers:     []corev1.Container{newInjectedContainer("app")},
+			},
+		},
+		{
+			name:       "init containers",
+			podSpec:    initPodSpec,
+			strategies: failStrategies,
+			inject: func(i *Injector) {
+				i.WithInitContainers()
+				injectEnvAndVolumeMount(i)
+			},
+			want: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					newInjectedContainer("init"),
+					newContainer("istio-init", []corev1.EnvVar{appEnv}, []corev1.VolumeMount{injectedVolumeMount}),
+				},
+				Containers: []corev1.Container{newInjectedContainer("app")},
+			},
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "istio-init", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name:       "init containers selected by the selector",
+			podSpec:    initPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector(nil, "", []string{"istio-init"}),
+			inject: func(i *Injector) {
+				i.WithInitContainers()
+				injectEnvAndVolumeMount(i)
+			},
+			want: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					newInjectedContainer("init"),
+					newContainer("istio-init", []corev1.EnvVar{appEnv}, nil),
+				},
+				Containers: []corev1.Container{newInjectedContainer("app")},
+			},
+		},
+		{
+			name:       "only ephemeral containers",
+			podSpec:    ephemeralPodSpec,
+			strategies: failStrategies,
+			inject: func(i *Injector) {
+				i.WithOnlyEphemeralContainers("debugger")
+				injectEnvAndVolumeMount(i)
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{newContainer("app", nil, nil)},
+				EphemeralContainers: []corev1.EphemeralContainer{
+					newEphemeralContainer("debugger", []corev1.EnvVar{injectedEnv}, []corev1.VolumeMount{injectedVolumeMount}),
+					newEphemeralContainer("another-debugger", nil, nil),
+				},
+			},
+		},
+		{
+			// NOTE: The selector is for the containers of the pod spec, and ephemeral containers are specified by names.
+			name:       "only ephemeral containers regardless of the selector",
+			podSpec:    ephemeralPodSpec,
+			strategies: failStrategies,
+			selector:   newSelector([]string{"app"}, "", []string{"debugger"}),
+			inject: func(i *Injector) {
+				i.WithOnlyEphemeralContainers("debugger")
+				injectEnvAndVolumeMount(i)
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{newContainer("app", nil, nil)},
+				EphemeralContainers: []corev1.EphemeralContainer{
+					newEphemeralContainer("debugger", []corev1.EnvVar{injectedEnv}, []corev1.VolumeMount{injectedVolumeMount}),
+					newEphemeralContainer("another-debugger", nil, nil),
+				},
+			},
+		},
+		{
+			name: "ephemeral container with the KeepExisting strategy",
+			podSpec: corev1.PodSpec{
+				Containers: []corev1.Container{newContainer("app", nil, nil)},
+				EphemeralContainers: []corev1.EphemeralContainer{
+					newEphemeralContainer("debugger", []corev1.EnvVar{appEnv}, []corev1.VolumeMount{appVolumeMount}),
+				},
+			},
+			strategies: MergeStrategies{VolumeMounts: MergeStrategyKeepExisting, Envs: MergeStrategyKeepExisting},
+			inject: func(i *Injector) {
+				i.WithOnlyEphemeralContainers("debugger")
+				injectEnvAndVolumeMount(i)
+			},
+			want: corev1.PodSpec{
+				Containers: []corev1.Container{newContainer("app", nil, nil)},
+				EphemeralContainers: []corev1.EphemeralContainer{
+					newEphemeralContainer("debugger", []corev1.EnvVar{appEnv}, []corev1.VolumeMount{appVolumeMount}),
+				},
+			},
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "debugger", Strategy: MergeStrategyKeepExisting},
+				{Field: "volumeMounts", Name: "data", ContainerName: "debugger", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name:       "no ephemeral containers",
+			podSpec:    ephemeralPodSpec,
+			strategies: failStrategies,
+			inject: func(i *Injector) {
+				i.WithOnlyEphemeralContainers()
+				injectEnvAndVolumeMount(i)
+			},
+			want: ephemeralPodSpec,
+		},
+		{
+			name:       "labels and annotations added",
+			strategies: MergeStrategies{PodTemplate: MergeStrategyFail},
+			injectMeta: func(i *Injector, meta *metav1.ObjectMeta) {
+				i.InjectLabels(meta, map[string]string{"team": "logging"})
+				i.InjectAnnotations(meta, map[string]string{"example.com/scrape": "true"})
+			},
+			wantMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{"team": "logging"},
+				Annotations: map[string]string{"example.com/scrape": "true"},
+			},
+		},
+		{
+			name:       "labels with the Replace strategy",
+			strategies: MergeStrategies{PodTemplate: MergeStrategyReplace},
+			meta:       metav1.ObjectMeta{Labels: map[string]string{"app": "app", "team": "app", "tier": "app"}},
+			injectMeta: func(i *Injector, meta *metav1.ObjectMeta) {
+				i.InjectLabels(meta, map[string]string{"tier": "logging", "app": "app", "team": "logging"})
+			},
+			wantMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "app", "team": "logging", "tier": "logging"}},
+			wantConflicts: []Conflict{
+				{Field: "labels", Name: "team", Strategy: MergeStrategyReplace},
+				{Field: "labels", Name: "tier", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name:       "labels with the KeepExisting strategy",
+			strategies: MergeStrategies{PodTemplate: MergeStrategyKeepExisting},
+			meta:       metav1.ObjectMeta{Labels: map[string]string{"team": "app"}},
+			injectMeta: func(i *Injector, meta *metav1.ObjectMeta) {
+				i.InjectLabels(meta, map[string]string{"team": "logging", "logging": "true"})
+			},
+			wantMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "app", "logging": "true"}},
+			wantConflicts: []Conflict{
+				{Field: "labels", Name: "team", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name:       "annotations with the Fail strategy",
+			strategies: MergeStrategies{PodTemplate: MergeStrategyFail},
+			meta:       metav1.ObjectMeta{Annotations: map[string]string{"example.com/scrape": "false"}},
+			injectMeta: func(i *Injector, meta *metav1.ObjectMeta) {
+				i.InjectAnnotations(meta, map[string]string{"example.com/scrape": "true"})
+			},
+			wantMeta: metav1.ObjectMeta{Annotations: map[string]string{"example.com/scrape": "false"}},
+			wantConflicts: []Conflict{
+				{Field: "annotations", Name: "example.com/scrape", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name:       "securityContext added",
+			strategies: MergeStrategies{PodTemplate: MergeStrategyFail},
+			inject: func(i *Injector) {
+				i.InjectSecurityContext(&corev1.PodSecurityContext{FSGroup: int64Ptr(2000)})
+			},
+			want: corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(2000)}},
+		},
+		{
+			name:       "securityContext with the Replace strategy",
+			podSpec:    corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{RunAsUser: int64Ptr(1000)}},
+			strategies: MergeStrategies{PodTemplate: MergeStrategyReplace},
+			inject: func(i *Injector) {
+				i.InjectSecurityContext(&corev1.PodSecurityContext{RunAsUser: int64Ptr(2000), FSGroup: int64Ptr(2000)})
+			},
+			want: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{RunAsUser: int64Ptr(2000), FSGroup: int64Ptr(2000)},
+			},
+			wantConflicts: []Conflict{
+				{Field: "securityContext", Name: "runAsUser", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name: "securityContext with the KeepExisting strategy",
+			podSpec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{RunAsUser: int64Ptr(1000), FSGroup: int64Ptr(2000)},
+			},
+			strategies: MergeStrategies{PodTemplate: MergeStrategyKeepExisting},
+			inject: func(i *Injector) {
+				i.InjectSecurityContext(&corev1.PodSecurityContext{RunAsUser: int64Ptr(2000), FSGroup: int64Ptr(2000)})
+			},
+			want: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{RunAsUser: int64Ptr(1000), FSGroup: int64Ptr(2000)},
+			},
+			wantConflicts: []Conflict{
+				{Field: "securityContext", Name: "runAsUser", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name:       "tolerations with the Fail strategy",
+			podSpec:    corev1.PodSpec{Tolerations: appTolerations},
+			strategies: MergeStrategies{PodTemplate: MergeStrategyFail},
+			inject: func(i *Injector) {
+				i.InjectToleration(&injectedToleration)
+				noExecute := injectedToleration.DeepCopy()
+				noExecute.Effect = corev1.TaintEffectNoExecute
+				i.InjectToleration(noExecute)
+			},
+			want: corev1.PodSpec{
+				Tolerations: append(appTolerations, corev1.Toleration{
+					Key:      "dedicated",
+					Operator: corev1.TolerationOpEqual,
+					Value:    "logging",
+					Effect:   corev1.TaintEffectNoExecute,
+				}),
+			},
+			wantConflicts: []Conflict{
+				{Field: "tolerations", Name: "dedicated:NoSchedule", Strategy: MergeStrategyFail},
+			},
+		},
+		{
+			name:       "tolerations with the Replace strategy",
+			podSpec:    corev1.PodSpec{Tolerations: appTolerations},
+			strategies: MergeStrategies{PodTemplate: MergeStrategyReplace},
+			inject:     func(i *Injector) { i.InjectToleration(&injectedToleration) },
+			want:       corev1.PodSpec{Tolerations: []corev1.Toleration{injectedToleration}},
+			wantConflicts: []Conflict{
+				{Field: "tolerations", Name: "dedicated:NoSchedule", Strategy: MergeStrategyReplace},
+			},
+		},
+		{
+			name: "resources with the KeepExisting strategy",
+			podSpec: newPodSpec(corev1.Container{
+				Name:      "sidecar",
+				Resources: newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "1"}),
+			}),
+			strategies: MergeStrategies{PodTemplate: MergeStrategyKeepExisting},
+			inject: func(i *Injector) {
+				r := newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "2", corev1.ResourceMemory: "1Gi"})
+				i.InjectResources("sidecar", &r)
+			},
+			want: newPodSpec(corev1.Container{
+				Name:      "sidecar",
+				Resources: newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "1", corev1.ResourceMemory: "1Gi"}),
+			}),
+			wantConflicts: []Conflict{
+				{Field: "resources", Name: "requests.cpu", ContainerName: "sidecar", Strategy: MergeStrategyKeepExisting},
+			},
+		},
+		{
+			name: "resources of the same quantities",
+			podSpec: newPodSpec(corev1.Container{
+				Name:      "sidecar",
+				Resources: newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "1"}),
+			}),
+			strategies: MergeStrategies{PodTemplate: MergeStrategyFail},
+			inject: func(i *Injector) {
+				r := newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "1000m"})
+				i.InjectResources("sidecar", &r)
+			},
+			want: newPodSpec(corev1.Container{
+				Name:      "sidecar",
+				Resources: newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "1"}),
+			}),
+		},
+		{
+			name:       "resources of a missing container",
+			podSpec:    newPodSpec(newContainer("app", nil, nil)),
+			strategies: MergeStrategies{PodTemplate: MergeStrategyFail},
+			inject: func(i *Injector) {
+				r := newResources(map[corev1.ResourceName]string{corev1.ResourceCPU: "1"})
+				i.InjectResources("sidecar", &r)
+			},
+			want: newPodSpec(newContainer("app", nil, nil)),
+		},
+		{
+			// NOTE: The strategy for the pod template overrides is independent of the strategies of the other fields.
+			name: "pod template overrides with the other strategies",
+			podSpec: corev1.PodSpec{
+				Containers:  []corev1.Container{newContainer("app", []corev1.EnvVar{appEnv}, nil)},
+				Tolerations: appTolerations,
+			},
+			strategies: MergeStrategies{Envs: MergeStrategyFail, PodTemplate: MergeStrategyReplace},
+			meta:       metav1.ObjectMeta{Labels: map[string]string{"team": "app"}},
+			inject: func(i *Injector) {
+				i.InjectEnv(&injectedEnv)
+				i.InjectToleration(&injectedToleration)
+			},
+			injectMeta: func(i *Injector, meta *metav1.ObjectMeta) {
+				i.InjectLabels(meta, map[string]string{"team": "logging"})
+			},
+			want: corev1.PodSpec{
+				Containers:  []corev1.Container{newContainer("app", []corev1.EnvVar{appEnv}, nil)},
+				Tolerations: []corev1.Toleration{injectedToleration},
+			},
+			wantMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "logging"}},
+			wantConflicts: []Conflict{
+				{Field: "env", Name: "ENV", ContainerName: "app", Strategy: MergeStrategyFail},
+				{Field: "tolerations", Name: "dedicated:NoSchedule", Strategy: MergeStrategyReplace},
+				{Field: "labels", Name: "team", Strategy: MergeStrategyReplace},
+			},
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			podSpec := c.podSpec.DeepCopy()
+			i := NewInjector(podSpec, c.strategies).WithContainerSelector(c.selector)
+			if c.inject != nil {
+				c.inject(i)
+			}
+			meta := c.meta.DeepCopy()
+			if c.injectMeta != nil {
+				c.injectMeta(i, meta)
+			}
+			if !apiequality.Semantic.DeepEqual(*podSpec, c.want) {
+				t.Errorf("expected %+v, but got %+v", c.want, *podSpec)
+			}
+			if !apiequality.Semantic.DeepEqual(*meta, c.wantMeta) {
+				t.Errorf("expected %+v, but got %+v", c.wantMeta, *meta)
+			}
+			if !reflect.DeepEqual(i.Conflicts(), c.wantConflicts) {
+				t.Errorf("expected conflicts=%+v, but got %+v", c.wantConflicts, i.Conflicts())
+			}
+		})
+	}
+
+	t.Run("invalid nameRegex", func(t *testing.T) {
+		if _, err := NewContainerSelector(nil, "app(", nil); err == nil {
+			t.Errorf("expected an error for the invalid nameRegex, but got nil")
+		}
+	})
+}
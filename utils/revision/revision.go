@@ -0,0 +1,150 @@
+package revision
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	hashutils "github.com/st-tech/fluent-pvc-operator/utils/hash"
+)
+
+// NOTE: Give up when the hash of the spec collides this many times. It never happens in practice.
+const maxCollisionCount = 10
+
+// GetOrCreateFluentPVCRevision returns the FluentPVCRevision that has the same spec as the FluentPVC.
+// A new FluentPVCRevision is created with the next revision number if it does not exist, and the existing
+// one is renumbered to the next revision number if the FluentPVC is reverted to its spec.
+func GetOrCreateFluentPVCRevision(
+	ctx context.Context,
+	c client.Client,
+	scheme *runtime.Scheme,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+) (*fluentpvcv1alpha1.FluentPVCRevision, error) {
+	for collisionCount := int32(0); collisionCount < maxCollisionCount; collisionCount++ {
+		name := ComputeFluentPVCRevisionName(fpvc, collisionCount)
+		r := &fluentpvcv1alpha1.FluentPVCRevision{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, r); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			return createFluentPVCRevision(ctx, c, scheme, fpvc, name)
+		}
+		// NOTE: The revision may be left by a deleted FluentPVC of the same name until it is garbage collected.
+		if !IsFluentPVCRevisionOf(r, fpvc) || !apiequality.Semantic.DeepEqual(r.Spec, fpvc.Spec) || !r.DeletionTimestamp.IsZero() {
+			continue
+		}
+		return renumberFluentPVCRevision(ctx, c, fpvc, r)
+	}
+	return nil, xerrors.Errorf("Cannot compute a unique FluentPVCRevision name for FluentPVC='%s'.", fpvc.Name)
+}
+
+// ComputeFluentPVCRevisionName returns the name of the FluentPVCRevision for the spec of the FluentPVC.
+func ComputeFluentPVCRevisionName(fpvc *fluentpvcv1alpha1.FluentPVC, collisionCount int32) string {
+	return hashutils.ComputeName(fpvc.Name, hashutils.ComputeHash(&fpvc.Spec, &collisionCount))
+}
+
+func createFluentPVCRevision(
+	ctx context.Context,
+	c client.Client,
+	scheme *runtime.Scheme,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	name string,
+) (*fluentpvcv1alpha1.FluentPVCRevision, error) {
+	revision, err := findLatestRevision(ctx, c, fpvc)
+	if err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+
+	r := &fluentpvcv1alpha1.FluentPVCRevision{}
+	r.SetName(name)
+	r.Spec = *fpvc.Spec.DeepCopy()
+	r.Revision = revision + 1
+	if err := ctrl.SetControllerReference(fpvc, r, scheme); err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if err := c.Create(ctx, r); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			// NOTE: Created by another webhook or controller concurrently.
+			if err := c.Get(ctx, client.ObjectKey{Name: name}, r); err != nil {
+				return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			return r, nil
+		}
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return r, nil
+}
+
+// renumberFluentPVCRevision updates the revision number of the FluentPVCRevision to the next one if it is not
+// the latest, like ControllerRevisions of StatefulSets, so that the revision number reverted to an earlier spec
+// is still the largest one.
+func renumberFluentPVCRevision(
+	ctx context.Context,
+	c client.Client,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	r *fluentpvcv1alpha1.FluentPVCRevision,
+) (*fluentpvcv1alpha1.FluentPVCRevision, error) {
+	revision, err := findLatestRevision(ctx, c, fpvc)
+	if err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if r.Revision >= revision {
+		return r, nil
+	}
+	r.Revision = revision + 1
+	if err := c.Update(ctx, r); err != nil {
+		if apierrors.IsConflict(err) {
+			// NOTE: Renumbered by another webhook or controller concurrently.
+			if err := c.Get(ctx, client.ObjectKeyFromObject(r), r); err != nil {
+				return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+			}
+			return r, nil
+		}
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return r, nil
+}
+
+// findLatestRevision returns the largest revision number of the FluentPVCRevisions of the FluentPVC, or 0 if
+// there are no FluentPVCRevisions.
+func findLatestRevision(ctx context.Context, c client.Client, fpvc *fluentpvcv1alpha1.FluentPVC) (int64, error) {
+	revisions := &fluentpvcv1alpha1.FluentPVCRevisionList{}
+	if err := c.List(ctx, revisions); err != nil {
+		return 0, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	var revision int64
+	for _, r := range revisions.Items {
+		if IsFluentPVCRevisionOf(&r, fpvc) && r.Revision > revision {
+			revision = r.Revision
+		}
+	}
+	return revision, nil
+}
+
+// IsFluentPVCRevisionOf returns true if the FluentPVCRevision is controlled by the FluentPVC.
+func IsFluentPVCRevisionOf(r *fluentpvcv1alpha1.FluentPVCRevision, fpvc *fluentpvcv1alpha1.FluentPVC) bool {
+	return metav1.IsControlledBy(r, fpvc)
+}
+
+// EqualPodInjection returns true if the parts of the specs that are injected into pods are the same.
+func EqualPodInjection(a, b *fluentpvcv1alpha1.FluentPVCSpec) bool {
+	return apiequality.Semantic.DeepEqual(a.SidecarContainerTemplate, b.SidecarContainerTemplate) &&
+		apiequality.Semantic.DeepEqual(a.CommonEnvs, b.CommonEnvs) &&
+		apiequality.Semantic.DeepEqual(a.CommonVolumes, b.CommonVolumes) &&
+		apiequality.Semantic.DeepEqual(a.CommonVolumeMounts, b.CommonVolumeMounts) &&
+		apiequality.Semantic.DeepEqual(a.MergeStrategies, b.MergeStrategies) &&
+		apiequality.Semantic.DeepEqual(a.TargetContainers, b.TargetContainers) &&
+		apiequality.Semantic.DeepEqual(a.PVCVolumeMountSubPaths, b.PVCVolumeMountSubPaths) &&
+		a.InjectInitContainers == b.InjectInitContainers &&
+		apiequality.Semantic.DeepEqual(a.PodTemplateOverrides, b.PodTemplateOverrides) &&
+		a.PVCVolumeName == b.PVCVolumeName &&
+		a.PVCVolumeMountPath == b.PVCVolumeMountPath
+}
@@ -0,0 +1,171 @@
+package revision
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := fluentpvcv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func newFluentPVC(uid, image string) *fluentpvcv1alpha1.FluentPVC {
+	return &fluentpvcv1alpha1.FluentPVC{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: fluentpvcv1alpha1.GroupVersion.String(),
+			Kind:       "FluentPVC",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc", UID: types.UID(uid)},
+		Spec: fluentpvcv1alpha1.FluentPVCSpec{
+			SidecarContainerTemplate: corev1.Container{Name: "sidecar", Image: image},
+		},
+	}
+}
+
+func TestGetOrCreateFluentPVCRevision(t *testing.T) {
+	ctx := context.Background()
+	scheme := newScheme(t)
+	fpvc := newFluentPVC("test-uid", "fluentd:v1")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(fpvc).Build()
+
+	getOrCreate := func(t *testing.T, image string, wantRevision int64) *fluentpvcv1alpha1.FluentPVCRevision {
+		t.Helper()
+		fpvc.Spec.SidecarContainerTemplate.Image = image
+		r, err := GetOrCreateFluentPVCRevision(ctx, c, scheme, fpvc)
+		if err != nil {
+			t.Fatalf("expected no error, but got %+v", err)
+		}
+		if r.Revision != wantRevision {
+			t.Errorf("expected revision=%d, but got %d", wantRevision, r.Revision)
+		}
+		if !IsFluentPVCRevisionOf(r, fpvc) {
+			t.Errorf("expected the revision to be controlled by the FluentPVC, but got %+v", r.OwnerReferences)
+		}
+		if r.Spec.SidecarContainerTemplate.Image != image {
+			t.Errorf("expected the image='%s', but got '%s'", image, r.Spec.SidecarContainerTemplate.Image)
+		}
+		stored := &fluentpvcv1alpha1.FluentPVCRevision{}
+		if err := c.Get(ctx, client.ObjectKey{Name: r.Name}, stored); err != nil {
+			t.Fatalf("expected the revision to be stored, but got %+v", err)
+		}
+		if stored.Revision != wantRevision {
+			t.Errorf("expected the stored revision=%d, but got %d", wantRevision, stored.Revision)
+		}
+		return r
+	}
+
+	v1 := getOrCreate(t, "fluentd:v1", 1)
+	if r := getOrCreate(t, "fluentd:v1", 1); r.Name != v1.Name {
+		t.Errorf("expected the same revision='%s' for the same spec, but got '%s'", v1.Name, r.Name)
+	}
+	v2 := getOrCreate(t, "fluentd:v2", 2)
+	if v2.Name == v1.Name {
+		t.Errorf("expected a new revision for the changed spec, but got '%s'", v2.Name)
+	}
+	// NOTE: The reverted spec reuses the revision, but its number is bumped like ControllerRevisions.
+	if r := getOrCreate(t, "fluentd:v1", 3); r.Name != v1.Name {
+		t.Errorf("expected the revision='%s' to be reused for the reverted spec, but got '%s'", v1.Name, r.Name)
+	}
+	if r := getOrCreate(t, "fluentd:v1", 3); r.Name != v1.Name {
+		t.Errorf("expected the revision='%s' to be kept for the same spec, but got '%s'", v1.Name, r.Name)
+	}
+	if r := getOrCreate(t, "fluentd:v2", 4); r.Name != v2.Name {
+		t.Errorf("expected the revision='%s' to be reused for the reverted spec, but got '%s'", v2.Name, r.Name)
+	}
+
+	revisions := &fluentpvcv1alpha1.FluentPVCRevisionList{}
+	if err := c.List(ctx, revisions); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions.Items) != 2 {
+		t.Errorf("expected 2 revisions, but got %d", len(revisions.Items))
+	}
+}
+
+func TestGetOrCreateFluentPVCRevisionOfRecreatedFluentPVC(t *testing.T) {
+	ctx := context.Background()
+	scheme := newScheme(t)
+	fpvc := newFluentPVC("test-uid", "fluentd:v1")
+	// NOTE: The revision of the deleted FluentPVC of the same name remains until it is garbage collected.
+	deleted := newFluentPVC("deleted-uid", "fluentd:v1")
+	left := &fluentpvcv1alpha1.FluentPVCRevision{}
+	left.SetName(ComputeFluentPVCRevisionName(deleted, 0))
+	left.Spec = deleted.Spec
+	left.Revision = 5
+	left.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(deleted, fluentpvcv1alpha1.GroupVersion.WithKind("FluentPVC")),
+	})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(fpvc, left).Build()
+
+	r, err := GetOrCreateFluentPVCRevision(ctx, c, scheme, fpvc)
+	if err != nil {
+		t.Fatalf("expected no error, but got %+v", err)
+	}
+	if r.Name != ComputeFluentPVCRevisionName(fpvc, 1) {
+		t.Errorf("expected the name with the next collision count, but got '%s'", r.Name)
+	}
+	if r.Revision != 1 {
+		t.Errorf("expected revision=1 ignoring the revisions of the deleted FluentPVC, but got %d", r.Revision)
+	}
+}
+
+func TestEqualPodInjection(t *testing.T) {
+	base := newFluentPVC("test-uid", "fluentd:v1").Spec
+	cases := []struct {
+		name   string
+		modify func(s *fluentpvcv1alpha1.FluentPVCSpec)
+		want   bool
+	}{
+		{name: "same spec", modify: func(s *fluentpvcv1alpha1.FluentPVCSpec) {}, want: true},
+		{
+			name:   "sidecar container",
+			modify: func(s *fluentpvcv1alpha1.FluentPVCSpec) { s.SidecarContainerTemplate.Image = "fluentd:v2" },
+			want:   false,
+		},
+		{
+			name:   "common envs",
+			modify: func(s *fluentpvcv1alpha1.FluentPVCSpec) { s.CommonEnvs = []corev1.EnvVar{{Name: "ENV", Value: "v"}} },
+			want:   false,
+		},
+		{
+			name:   "volume mount path",
+			modify: func(s *fluentpvcv1alpha1.FluentPVCSpec) { s.PVCVolumeMountPath = "/mnt/changed" },
+			want:   false,
+		},
+		{
+			name:   "pvc spec template not injected into pods",
+			modify: func(s *fluentpvcv1alpha1.FluentPVCSpec) { s.PVCSpecTemplate.VolumeName = "changed" },
+			want:   true,
+		},
+		{
+			name: "finalizer job not injected into pods",
+			modify: func(s *fluentpvcv1alpha1.FluentPVCSpec) {
+				s.PVCFinalizerJobSpecTemplate.Template.Spec.Containers = []corev1.Container{{Name: "finalizer"}}
+			},
+			want: true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			modified := base.DeepCopy()
+			c.modify(modified)
+			if got := EqualPodInjection(&base, modified); got != c.want {
+				t.Errorf("expected %t, but got %t", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package snapshot
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VolumeSnapshots are handled as unstructured objects because the CRDs are installed separately from
+// Kubernetes (https://github.com/kubernetes-csi/external-snapshotter).
+var (
+	VolumeSnapshotGVK     = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
+	VolumeSnapshotListGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshotList"}
+)
+
+func NewVolumeSnapshot() *unstructured.Unstructured {
+	vs := &unstructured.Unstructured{}
+	vs.SetGroupVersionKind(VolumeSnapshotGVK)
+	return vs
+}
+
+func NewVolumeSnapshotList() *unstructured.UnstructuredList {
+	l := &unstructured.UnstructuredList{}
+	l.SetGroupVersionKind(VolumeSnapshotListGVK)
+	return l
+}
+
+// SetSource sets the PVC to take the snapshot of and the VolumeSnapshotClass.
+func SetSource(vs *unstructured.Unstructured, pvcName, volumeSnapshotClassName string) error {
+	if err := unstructured.SetNestedField(vs.Object, pvcName, "spec", "source", "persistentVolumeClaimName"); err != nil {
+		return err
+	}
+	return unstructured.SetNestedField(vs.Object, volumeSnapshotClassName, "spec", "volumeSnapshotClassName")
+}
+
+// GetSourcePVCName returns the name of the PVC that the snapshot is taken of.
+func GetSourcePVCName(vs *unstructured.Unstructured) string {
+	name, _, _ := unstructured.NestedString(vs.Object, "spec", "source", "persistentVolumeClaimName")
+	return name
+}
+
+func IsReadyToUse(vs *unstructured.Unstructured) bool {
+	ready, _, _ := unstructured.NestedBool(vs.Object, "status", "readyToUse")
+	return ready
+}
+
+// GetErrorMessage returns the message of the last error of the snapshot, or an empty string.
+func GetErrorMessage(vs *unstructured.Unstructured) string {
+	message, _, _ := unstructured.NestedString(vs.Object, "status", "error", "message")
+	return message
+}
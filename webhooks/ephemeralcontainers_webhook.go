@@ -0,0 +1,149 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/xerrors"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	podutils "github.com/st-tech/fluent-pvc-operator/utils/pod"
+)
+
+// NOTE: failurePolicy is ignore so as not to prevent debugging pods with ephemeral containers when the operator is unavailable.
+//+kubebuilder:webhook:path=/pod/ephemeralcontainers/mutate,mutating=true,failurePolicy=ignore,sideEffects=None,groups=core,resources=pods/ephemeralcontainers,verbs=update,versions=v1,name=pod-ephemeralcontainers-mutation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
+
+type ephemeralContainersMutator struct {
+	client.Client
+	decoder *admission.Decoder
+}
+
+func NewEphemeralContainersMutator(c client.Client) admission.Handler {
+	return &ephemeralContainersMutator{Client: c}
+}
+
+func (m *ephemeralContainersMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrl.LoggerFrom(ctx).WithName("ephemeralContainersMutator").WithName("Handle")
+	pod := &corev1.Pod{}
+	if err := m.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, pod); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	fpvcName, ok := pod.Labels[constants.PodLabelFluentPVCName]
+	if !ok {
+		return admission.Allowed("")
+	}
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := m.Get(ctx, client.ObjectKey{Name: fpvcName}, fpvc); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if !fpvc.Spec.InjectEphemeralContainers {
+		return admission.Allowed("")
+	}
+
+	// NOTE: The subresource receives an EphemeralContainers object before Kubernetes v1.22, and a Pod object after that.
+	var obj interface{}
+	var podSpec, oldPodSpec *corev1.PodSpec
+	switch req.Kind.Kind {
+	case "EphemeralContainers":
+		ec, oldEC := &corev1.EphemeralContainers{}, &corev1.EphemeralContainers{}
+		if err := m.decoder.Decode(req, ec); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := m.decoder.DecodeRaw(req.OldObject, oldEC); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		podSpec = &corev1.PodSpec{EphemeralContainers: ec.EphemeralContainers}
+		oldPodSpec = &corev1.PodSpec{EphemeralContainers: oldEC.EphemeralContainers}
+		obj = ec
+	case "Pod":
+		p, oldP := &corev1.Pod{}, &corev1.Pod{}
+		if err := m.decoder.Decode(req, p); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := m.decoder.DecodeRaw(req.OldObject, oldP); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		podSpec, oldPodSpec = &p.Spec, &oldP.Spec
+		obj = p
+	default:
+		return admission.Errored(http.StatusBadRequest, xerrors.New(fmt.Sprintf("Unexpected kind='%s'.", req.Kind.Kind)))
+	}
+
+	names := []string{}
+	oldNames := map[string]bool{}
+	for _, c := range oldPodSpec.EphemeralContainers {
+		oldNames[c.Name] = true
+	}
+	for _, c := range podSpec.EphemeralContainers {
+		if !oldNames[c.Name] {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) == 0 {
+		return admission.Allowed("")
+	}
+
+	conflicts := injectFluentPVCIntoEphemeralContainers(podSpec, pod, fpvc, names)
+	if podutils.HasFailedConflict(conflicts) {
+		return deniedByConflicts(fpvc, conflicts)
+	}
+	if ec, ok := obj.(*corev1.EphemeralContainers); ok {
+		ec.EphemeralContainers = podSpec.EphemeralContainers
+	}
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	logger.Info(fmt.Sprintf(
+		"Patch ephemeral containers=%v of Pod='%s'(namespace='%s') by FluentPVC='%s'.",
+		names, pod.Name, req.Namespace, fpvc.Name,
+	))
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled).WithWarnings(conflictWarnings(conflicts)...)
+}
+
+// injectFluentPVCIntoEphemeralContainers injects the common envs and the volumeMounts into the ephemeral
+// containers of the names. Ephemeral containers cannot add volumes, so only the volumeMounts of the volumes
+// that the pod already has are injected.
+func injectFluentPVCIntoEphemeralContainers(
+	podSpec *corev1.PodSpec,
+	pod *corev1.Pod,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	names []string,
+) []podutils.Conflict {
+	volumes := map[string]bool{}
+	for _, v := range pod.Spec.Volumes {
+		volumes[v.Name] = true
+	}
+	injector := podutils.NewInjector(podSpec, podutils.MergeStrategies{
+		VolumeMounts: podutils.MergeStrategy(fpvc.Spec.MergeStrategies.VolumeMounts),
+		Envs:         podutils.MergeStrategy(fpvc.Spec.MergeStrategies.Envs),
+	}).WithOnlyEphemeralContainers(names...)
+	for _, vm := range fpvc.Spec.CommonVolumeMounts {
+		if volumes[vm.Name] {
+			injector.InjectVolumeMount(vm.DeepCopy())
+		}
+	}
+	if volumes[fpvc.Spec.PVCVolumeName] {
+		injector.InjectVolumeMountWithSubPaths(&corev1.VolumeMount{
+			Name:      fpvc.Spec.PVCVolumeName,
+			MountPath: fpvc.Spec.PVCVolumeMountPath,
+		}, fpvc.Spec.PVCVolumeMountSubPaths)
+	}
+	for _, e := range fpvc.Spec.CommonEnvs {
+		injector.InjectEnv(e.DeepCopy())
+	}
+	return injector.Conflicts()
+}
+
+func (m *ephemeralContainersMutator) InjectDecoder(d *admission.Decoder) error {
+	m.decoder = d
+	return nil
+}
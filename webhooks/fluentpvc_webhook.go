@@ -2,37 +2,135 @@ package webhooks
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	hashutils "github.com/st-tech/fluent-pvc-operator/utils/hash"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
 	podutils "github.com/st-tech/fluent-pvc-operator/utils/pod"
+	"golang.org/x/xerrors"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-func SetupFluentPVCWebhookWithManager(mgr ctrl.Manager) error {
-	mgr.GetWebhookServer().Register("/fluent-pvc/validate", &webhook.Admission{Handler: NewFluentPVCValidator(mgr.GetClient())})
+// SetupFluentPVCWebhookWithManager sets up the FluentPVC webhooks. FluentPVCs are validated against validationNamespaces
+// unless FluentPVC.spec.allowedNamespaces is specified. It defaults to the "default" namespace.
+func SetupFluentPVCWebhookWithManager(mgr ctrl.Manager, validationNamespaces ...string) error {
+	mgr.GetWebhookServer().Register("/fluent-pvc/mutate", &webhook.Admission{Handler: NewFluentPVCDefaulter(mgr.GetClient())})
+	mgr.GetWebhookServer().Register("/fluent-pvc/validate", &webhook.Admission{Handler: NewFluentPVCValidator(mgr.GetClient(), validationNamespaces...)})
 	return nil
 }
 
-//+kubebuilder:webhook:path=/fluent-pvc/validate,mutating=false,failurePolicy=fail,sideEffects=None,groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs,verbs=create,versions=v1alpha1,name=fluent-pvc-validation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
+//+kubebuilder:webhook:path=/fluent-pvc/mutate,mutating=true,failurePolicy=fail,sideEffects=None,groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs,verbs=create;update,versions=v1alpha1,name=fluent-pvc-mutation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
+
+type FluentPVCDefaulter struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+func NewFluentPVCDefaulter(c client.Client) admission.Handler {
+	return &FluentPVCDefaulter{Client: c}
+}
+
+func (d *FluentPVCDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrl.LoggerFrom(ctx).WithName("FluentPVCDefaulter").WithName("Handle")
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := d.decoder.Decode(req, fpvc); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !fpvc.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+
+	if fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected == nil {
+		fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = pointer.BoolPtr(true)
+	}
+	if fpvc.Spec.NodeFailureHandling.Policy == "" {
+		fpvc.Spec.NodeFailureHandling.Policy = fluentpvcv1alpha1.FluentPVCNodeFailurePolicyNone
+	}
+	if fpvc.Spec.NodeFailureHandling.GracePeriodSeconds == nil {
+		fpvc.Spec.NodeFailureHandling.GracePeriodSeconds = pointer.Int64Ptr(300)
+	}
+	if len(fpvc.Spec.PVCSpecTemplate.AccessModes) == 0 {
+		fpvc.Spec.PVCSpecTemplate.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	if fpvc.Spec.PVCFinalizerJobSpecTemplate.Template.Spec.RestartPolicy == "" {
+		fpvc.Spec.PVCFinalizerJobSpecTemplate.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	if fpvc.Spec.PVCSpecTemplate.StorageClassName == nil {
+		sc, err := findDefaultStorageClass(ctx, d.Client)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Cannot find the default StorageClass for FluentPVC='%s'.", fpvc.Name))
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		// NOTE: Leave it nil if there is no default StorageClass, and then FluentPVCValidator warns it.
+		if sc != nil {
+			fpvc.Spec.PVCSpecTemplate.StorageClassName = pointer.StringPtr(sc.Name)
+		}
+	}
+
+	marshaled, err := json.Marshal(fpvc)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+func (d *FluentPVCDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// findDefaultStorageClass returns the default StorageClass. The newest one is returned if there are multiple
+// default StorageClasses as Kubernetes does. nil is returned if there is none.
+func findDefaultStorageClass(ctx context.Context, c client.Client) (*storagev1.StorageClass, error) {
+	storageClasses := &storagev1.StorageClassList{}
+	if err := c.List(ctx, storageClasses); err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	var found *storagev1.StorageClass
+	for i := range storageClasses.Items {
+		sc := &storageClasses.Items[i]
+		if sc.Annotations[constants.StorageClassAnnotationIsDefaultClass] != "true" &&
+			sc.Annotations[constants.StorageClassAnnotationBetaIsDefaultClass] != "true" {
+			continue
+		}
+		if found != nil && !found.CreationTimestamp.Before(&sc.CreationTimestamp) {
+			continue
+		}
+		found = sc
+	}
+	return found, nil
+}
+
+//+kubebuilder:webhook:path=/fluent-pvc/validate,mutating=false,failurePolicy=fail,sideEffects=None,groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcs,verbs=create;update,versions=v1alpha1,name=fluent-pvc-validation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
 //+kubebuilder:rbac:groups="storage.k8s.io",resources=storageclasses,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=pods,verbs=create
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=create
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 type FluentPVCValidator struct {
-	Client  client.Client
-	decoder *admission.Decoder
+	Client     client.Client
+	Namespaces []string
+	decoder    *admission.Decoder
 }
 
-func NewFluentPVCValidator(c client.Client) admission.Handler {
-	return &FluentPVCValidator{Client: c}
+func NewFluentPVCValidator(c client.Client, namespaces ...string) admission.Handler {
+	if len(namespaces) == 0 {
+		namespaces = []string{corev1.NamespaceDefault}
+	}
+	return &FluentPVCValidator{Client: c, Namespaces: namespaces}
 }
 
 func (v *FluentPVCValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
@@ -43,6 +141,20 @@ func (v *FluentPVCValidator) Handle(ctx context.Context, req admission.Request)
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	if !fpvc.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+	if len(req.OldObject.Raw) != 0 {
+		old := &fluentpvcv1alpha1.FluentPVC{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// NOTE: Only the spec is validated, so updates of the metadata (e.g. the finalizer) are not blocked
+		//       by the FluentPVCs admitted before the validation is changed.
+		if apiequality.Semantic.DeepEqual(old.Spec, fpvc.Spec) {
+			return admission.Allowed("")
+		}
+	}
 
 	for _, m := range fpvc.Spec.PVCSpecTemplate.AccessModes {
 		if m != corev1.ReadWriteOnce {
@@ -50,47 +162,128 @@ func (v *FluentPVCValidator) Handle(ctx context.Context, req admission.Request)
 		}
 	}
 
-	storageClass := &storagev1.StorageClass{}
-	if err := v.Client.Get(ctx, client.ObjectKey{Name: *fpvc.Spec.PVCSpecTemplate.StorageClassName}, storageClass); err != nil {
-		logger.Error(err, fmt.Sprintf("Cannot Get StorageClass with FluentPVC.Spec.PVCSpecTemplate.StorageClassName: '%s'", *fpvc.Spec.PVCSpecTemplate.StorageClassName))
-		return admission.Errored(http.StatusInternalServerError, err)
+	if fpvc.Spec.NameTemplate != "" {
+		if _, err := renderNameTemplate(fpvc.Spec.NameTemplate, &nameTemplateData{
+			FluentPVCName:   fpvc.Name,
+			PodName:         fpvc.Name,
+			PodGenerateName: fpvc.Name + "-",
+			Namespace:       corev1.NamespaceDefault,
+		}); err != nil {
+			return admission.Denied(fmt.Sprintf("FluentPVC.spec.nameTemplate is invalid: %s", err))
+		}
 	}
 
-	j := &batchv1.Job{}
-	j.SetName(fpvc.Name)
-	j.SetNamespace(corev1.NamespaceDefault)
-	j.Spec = *fpvc.Spec.PVCFinalizerJobSpecTemplate.DeepCopy()
+	if _, err := podutils.NewContainerSelector(
+		fpvc.Spec.TargetContainers.Names,
+		fpvc.Spec.TargetContainers.NameRegex,
+		fpvc.Spec.TargetContainers.ExcludeNames,
+	); err != nil {
+		return admission.Denied(fmt.Sprintf("FluentPVC.spec.targetContainers.nameRegex is invalid: %s", err))
+	}
 
-	for _, e := range fpvc.Spec.CommonEnvs {
-		podutils.InjectOrReplaceEnv(&j.Spec.Template.Spec, e.DeepCopy())
+	for _, k := range []string{constants.PodLabelFluentPVCName, constants.PodLabelFluentPVCBindingName} {
+		if _, ok := fpvc.Spec.PodTemplateOverrides.Metadata.Labels[k]; ok {
+			return admission.Denied(fmt.Sprintf("FluentPVC.spec.podTemplateOverrides.metadata.labels must not contain '%s'.", k))
+		}
 	}
 
+	if fpvc.Spec.FinalizationMode == fluentpvcv1alpha1.FluentPVCFinalizationModeSnapshot && fpvc.Spec.SnapshotFinalization == nil {
+		return admission.Denied("FluentPVC.spec.snapshotFinalization is required if FluentPVC.spec.finalizationMode is 'Snapshot'.")
+	}
+	if fpvc.Spec.ZoneUnavailablePolicy == fluentpvcv1alpha1.FluentPVCZoneUnavailablePolicySnapshotAndRestore && fpvc.Spec.SnapshotFinalization == nil {
+		return admission.Denied("FluentPVC.spec.snapshotFinalization is required if FluentPVC.spec.zoneUnavailablePolicy is 'SnapshotAndRestore'.")
+	}
+
+	warnings := []string{}
+	if fpvc.Spec.PVCSpecTemplate.StorageClassName == nil {
+		// NOTE: FluentPVCDefaulter sets the default StorageClass if it exists.
+		warnings = append(warnings, "fluent-pvc-operator: FluentPVC.spec.pvcSpecTemplate.storageClassName is not specified and there is no default StorageClass, so PVCs are bound only to the PersistentVolumes without a StorageClass.")
+	} else {
+		storageClass := &storagev1.StorageClass{}
+		if err := v.Client.Get(ctx, client.ObjectKey{Name: *fpvc.Spec.PVCSpecTemplate.StorageClassName}, storageClass); err != nil {
+			logger.Error(err, fmt.Sprintf("Cannot Get StorageClass with FluentPVC.Spec.PVCSpecTemplate.StorageClassName: '%s'", *fpvc.Spec.PVCSpecTemplate.StorageClassName))
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
+	if fpvc.Spec.AdmissionFailurePolicy == fluentpvcv1alpha1.FluentPVCAdmissionFailurePolicyIgnore {
+		// NOTE: The webhook which mutates the pod is chosen by the pod label before the FluentPVC is read, so only
+		//       the label makes the pods admitted while the pod webhook is unavailable.
+		warnings = append(warnings, fmt.Sprintf(
+			"fluent-pvc-operator: FluentPVC.spec.admissionFailurePolicy 'Ignore' applies only to the errors in the pod webhook. Label the Pods with '%s: Ignore' to admit them while the pod webhook is unavailable.",
+			constants.PodLabelAdmissionFailurePolicy,
+		))
+	}
+
+	// NOTE: FluentPVC names can be longer than the names of the objects derived from the FluentPVC.
+	name := hashutils.ComputeName(fpvc.Name)
+
+	namespaces := v.Namespaces
+	if len(fpvc.Spec.AllowedNamespaces) > 0 {
+		namespaces = fpvc.Spec.AllowedNamespaces
+	}
+	// NOTE: Invalid specs are denied because they are invalid in any namespaces. The other errors depend on
+	//       the namespaces (e.g. ResourceQuotas, LimitRanges and Pod Security admission), so they are warned.
+	for _, ns := range namespaces {
+		if err := v.Client.Get(ctx, client.ObjectKey{Name: ns}, &corev1.Namespace{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			warnings = append(warnings, fmt.Sprintf("fluent-pvc-operator: namespace '%s' is not found, so FluentPVC is not validated in it.", ns))
+			continue
+		}
+		if err := v.dryRunCreate(ctx, fpvc, name, ns); err != nil {
+			if apierrors.IsInvalid(err) {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			warnings = append(warnings, fmt.Sprintf("fluent-pvc-operator: FluentPVC cannot run in namespace '%s': %s", ns, err))
+		}
+	}
+
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// dryRunCreate creates the finalizer Job, the PVC and the Pod from the FluentPVC in the namespace in dry-run mode.
+func (v *FluentPVCValidator) dryRunCreate(ctx context.Context, fpvc *fluentpvcv1alpha1.FluentPVC, name, namespace string) error {
+	logger := ctrl.LoggerFrom(ctx).WithName("FluentPVCValidator").WithName("dryRunCreate")
+
+	j := &batchv1.Job{}
+	j.SetName(name)
+	j.SetNamespace(namespace)
+	j.Spec = *fpvc.Spec.PVCFinalizerJobSpecTemplate.DeepCopy()
+	jobutils.InjectFluentPVC(&j.Spec.Template.Spec, &fpvc.Spec, name)
+
 	if err := v.Client.Create(ctx, j, client.DryRunAll); err != nil {
-		logger.Error(err, fmt.Sprintf("JobSpec is invalid. FluentPVC Name: '%s'", fpvc.Name))
-		return admission.Errored(http.StatusInternalServerError, err)
+		logger.Error(err, fmt.Sprintf("JobSpec is invalid. FluentPVC Name: '%s', Namespace: '%s'", fpvc.Name, namespace))
+		return err
 	}
 
 	pvc := &corev1.PersistentVolumeClaim{}
-	pvc.SetName(fpvc.Name)
-	pvc.SetNamespace(corev1.NamespaceDefault)
+	pvc.SetName(name)
+	pvc.SetNamespace(namespace)
 	pvc.Spec = *fpvc.Spec.PVCSpecTemplate.DeepCopy()
 
 	if err := v.Client.Create(ctx, pvc, client.DryRunAll); err != nil {
-		logger.Error(err, fmt.Sprintf("PVCSpec is invalid. FluentPVC Name: '%s'", fpvc.Name))
-		return admission.Errored(http.StatusInternalServerError, err)
+		logger.Error(err, fmt.Sprintf("PVCSpec is invalid. FluentPVC Name: '%s', Namespace: '%s'", fpvc.Name, namespace))
+		return err
 	}
 
+	// NOTE: Build the pod by the same injection as podMutator, so that the errors of the injected items
+	//       (e.g. a volumeMount of an unknown volume, or duplicated mount paths) are detected here.
+	//       The pod does not have the FluentPVC label so as not to be mutated by podMutator again.
 	pod := &corev1.Pod{}
-	pod.SetName(fpvc.Name)
-	pod.SetNamespace(corev1.NamespaceDefault)
-	pod.Spec.Containers = append(pod.Spec.Containers, *fpvc.Spec.SidecarContainerTemplate.DeepCopy())
+	pod.SetName(name)
+	pod.SetNamespace(namespace)
+	pod, _, err := injectFluentPVCByProvisioningMode(pod, fpvc, name)
+	if err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
 
 	if err := v.Client.Create(ctx, pod, client.DryRunAll); err != nil {
-		logger.Error(err, fmt.Sprintf("SidecarContainerSpec is invalid. FluentPVC Name: '%s'", fpvc.Name))
-		return admission.Errored(http.StatusInternalServerError, err)
+		logger.Error(err, fmt.Sprintf("Injected PodSpec is invalid. FluentPVC Name: '%s', Namespace: '%s'", fpvc.Name, namespace))
+		return err
 	}
-
-	return admission.Allowed("")
+	return nil
 }
 
 func (v *FluentPVCValidator) InjectDecoder(d *admission.Decoder) error {
@@ -11,9 +11,12 @@ import (
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
 )
 
 var _ = Describe("FluentPVC Validation Webhook", func() {
@@ -52,7 +55,7 @@ var _ = Describe("FluentPVC Validation Webhook", func() {
 					Command: []string{"echo", "test"},
 					Image:   "alpine",
 				},
-				DeletePodIfSidecarContainerTerminationDetected: true,
+				DeletePodIfSidecarContainerTerminationDetected: pointer.BoolPtr(true),
 				PVCFinalizerJobSpecTemplate: batchv1.JobSpec{
 					Template: corev1.PodTemplateSpec{
 						Spec: corev1.PodSpec{
@@ -102,6 +105,40 @@ var _ = Describe("FluentPVC Validation Webhook", func() {
 		Expect(err).Should(Succeed())
 
 	})
+	It("should default the FluentPVC.", func() {
+		ctx := context.Background()
+		const defaultStorageClassName = "test-default-storage-class"
+		sc := testStorageClass.DeepCopy()
+		sc.SetName(defaultStorageClassName)
+		sc.SetAnnotations(map[string]string{constants.StorageClassAnnotationIsDefaultClass: "true"})
+		err := k8sClient.Create(ctx, sc)
+		Expect(err).Should(Succeed())
+		defer func() {
+			err := k8sClient.Delete(ctx, sc)
+			Expect(err).Should(Succeed())
+		}()
+
+		fpvc := testFluentPVC.DeepCopy()
+		fpvc.Spec.PVCSpecTemplate.AccessModes = nil
+		fpvc.Spec.PVCSpecTemplate.StorageClassName = nil
+		fpvc.Spec.PVCFinalizerJobSpecTemplate.Template.Spec.RestartPolicy = ""
+		fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected = nil
+		err = k8sClient.Create(ctx, fpvc)
+		Expect(err).Should(Succeed())
+
+		fpvc = &fluentpvcv1alpha1.FluentPVC{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+		Expect(err).Should(Succeed())
+		Expect(fpvc.Spec.PVCSpecTemplate.AccessModes).Should(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}))
+		Expect(fpvc.Spec.PVCSpecTemplate.StorageClassName).ShouldNot(BeNil())
+		Expect(*fpvc.Spec.PVCSpecTemplate.StorageClassName).Should(BeEquivalentTo(defaultStorageClassName))
+		Expect(fpvc.Spec.PVCFinalizerJobSpecTemplate.Template.Spec.RestartPolicy).Should(BeEquivalentTo(corev1.RestartPolicyNever))
+		Expect(fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected).ShouldNot(BeNil())
+		Expect(*fpvc.Spec.DeletePodIfSidecarContainerTerminationDetected).Should(BeTrue())
+		Expect(fpvc.Spec.NodeFailureHandling.Policy).Should(Equal(fluentpvcv1alpha1.FluentPVCNodeFailurePolicyNone))
+		Expect(fpvc.Spec.NodeFailureHandling.GracePeriodSeconds).ShouldNot(BeNil())
+		Expect(*fpvc.Spec.NodeFailureHandling.GracePeriodSeconds).Should(BeEquivalentTo(300))
+	})
 	It("should return a error when PVCSpecTemplate.AccessModes is invalid.", func() {
 		ctx := context.Background()
 		fpvc := testFluentPVC.DeepCopy()
@@ -115,6 +152,21 @@ var _ = Describe("FluentPVC Validation Webhook", func() {
 				" the request: Only 'ReadWriteOnce' is acceptable for FluentPVC.spec.pvcSpecTemplate.accessModes, but '[ReadOnlyMany]' is specified.",
 		))
 	})
+	It("should return a error when PVCSpecTemplate.AccessModes is updated to be invalid.", func() {
+		ctx := context.Background()
+		fpvc := testFluentPVC.DeepCopy()
+		err := k8sClient.Create(ctx, fpvc)
+		Expect(err).Should(Succeed())
+
+		fpvc.Spec.PVCSpecTemplate.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany}
+		err = k8sClient.Update(ctx, fpvc)
+
+		Expect(err).ShouldNot(Succeed())
+		Expect(err.Error()).Should(BeEquivalentTo(
+			"admission webhook \"fluent-pvc-validation-webhook.fluent-pvc-operator.tech.zozo.com\" denied" +
+				" the request: Only 'ReadWriteOnce' is acceptable for FluentPVC.spec.pvcSpecTemplate.accessModes, but '[ReadOnlyMany]' is specified.",
+		))
+	})
 	It("should return a error when PVCSpecTemplate.StorageClassName is invalid.", func() {
 		ctx := context.Background()
 		fpvc := testFluentPVC.DeepCopy()
@@ -168,4 +220,61 @@ var _ = Describe("FluentPVC Validation Webhook", func() {
 				" the request: Pod \"test-fluent-pvc\" is invalid: spec.containers[0].name: Required value",
 		))
 	})
+	It("should return a error when CommonVolumeMounts refer to an unknown volume.", func() {
+		ctx := context.Background()
+		fpvc := testFluentPVC.DeepCopy()
+
+		fpvc.Spec.CommonVolumeMounts = []corev1.VolumeMount{{Name: "unknown-volume", MountPath: "/mnt/unknown"}}
+		err := k8sClient.Create(ctx, fpvc)
+
+		Expect(err).ShouldNot(Succeed())
+		Expect(err.Error()).Should(ContainSubstring("Pod \"test-fluent-pvc\" is invalid"))
+		Expect(err.Error()).Should(ContainSubstring("Not found: \"unknown-volume\""))
+	})
+	It("should mount the PVC into the finalizer Job containers even if targetContainers does not match them.", func() {
+		ctx := context.Background()
+		fpvc := testFluentPVC.DeepCopy()
+		fpvc.Spec.TargetContainers.Names = []string{"test-app-container"}
+
+		j := &batchv1.Job{}
+		j.SetName("test-finalizer-job")
+		j.SetNamespace("default")
+		j.Spec = *fpvc.Spec.PVCFinalizerJobSpecTemplate.DeepCopy()
+		jobutils.InjectFluentPVC(&j.Spec.Template.Spec, &fpvc.Spec, "test-pvc")
+		err := k8sClient.Create(ctx, j)
+		Expect(err).Should(Succeed())
+		defer func() {
+			err := k8sClient.Delete(ctx, j, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			Expect(err).Should(Succeed())
+		}()
+
+		created := &batchv1.Job{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: j.Namespace, Name: j.Name}, created)
+		Expect(err).Should(Succeed())
+		Expect(created.Spec.Template.Spec.Volumes).Should(ContainElement(corev1.Volume{
+			Name: testVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "test-pvc"},
+			},
+		}))
+		Expect(created.Spec.Template.Spec.Containers).Should(HaveLen(1))
+		Expect(created.Spec.Template.Spec.Containers[0].Name).Should(BeEquivalentTo(testFinalizerContainerName))
+		Expect(created.Spec.Template.Spec.Containers[0].VolumeMounts).Should(ContainElement(corev1.VolumeMount{
+			Name:      testVolumeName,
+			MountPath: testMountPath,
+		}))
+	})
+	It("should return a error when SnapshotFinalization is not specified for the Snapshot FinalizationMode.", func() {
+		ctx := context.Background()
+		fpvc := testFluentPVC.DeepCopy()
+
+		fpvc.Spec.FinalizationMode = fluentpvcv1alpha1.FluentPVCFinalizationModeSnapshot
+		err := k8sClient.Create(ctx, fpvc)
+
+		Expect(err).ShouldNot(Succeed())
+		Expect(err.Error()).Should(BeEquivalentTo(
+			"admission webhook \"fluent-pvc-validation-webhook.fluent-pvc-operator.tech.zozo.com\" denied" +
+				" the request: FluentPVC.spec.snapshotFinalization is required if FluentPVC.spec.finalizationMode is 'Snapshot'.",
+		))
+	})
 })
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
+	"github.com/st-tech/fluent-pvc-operator/constants"
+)
+
+// NOTE: The controllers update FluentPVCBindings all the time, so the failurePolicy is Ignore not to block them while
+//       the webhook is unavailable. The actions admitted without the webhook have no requester, and are dropped
+//       by the controller.
+//+kubebuilder:webhook:path=/fluent-pvc-binding/mutate,mutating=true,failurePolicy=ignore,sideEffects=None,groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings,verbs=create;update,versions=v1alpha1,name=fluent-pvc-binding-mutation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
+
+func SetupFluentPVCBindingWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/fluent-pvc-binding/mutate", &webhook.Admission{Handler: NewFluentPVCBindingMutator(mgr.GetClient())})
+	return nil
+}
+
+// fluentPVCBindingMutator validates the action annotation, and records the user who requested the action
+// so that the requester cannot be spoofed.
+type fluentPVCBindingMutator struct {
+	client.Client
+	decoder *admission.Decoder
+}
+
+func NewFluentPVCBindingMutator(c client.Client) admission.Handler {
+	return &fluentPVCBindingMutator{Client: c}
+}
+
+func (m *fluentPVCBindingMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrl.LoggerFrom(ctx).WithName("fluentPVCBindingMutator").WithName("Handle")
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if err := m.decoder.Decode(req, b); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	action, ok := b.Annotations[constants.FluentPVCBindingAnnotationAction]
+	if !ok {
+		return admission.Allowed("")
+	}
+	switch fluentpvcv1alpha1.FluentPVCBindingAction(action) {
+	case fluentpvcv1alpha1.FluentPVCBindingActionRetry,
+		fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer,
+		fluentpvcv1alpha1.FluentPVCBindingActionRelease:
+	default:
+		return admission.Denied(fmt.Sprintf(
+			"Annotation '%s' must be one of '%s', '%s' and '%s', but '%s' is specified.",
+			constants.FluentPVCBindingAnnotationAction,
+			fluentpvcv1alpha1.FluentPVCBindingActionRetry,
+			fluentpvcv1alpha1.FluentPVCBindingActionRerunFinalizer,
+			fluentpvcv1alpha1.FluentPVCBindingActionRelease,
+			action,
+		))
+	}
+
+	old := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if len(req.OldObject.Raw) != 0 {
+		if err := m.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if old.Annotations[constants.FluentPVCBindingAnnotationAction] == action &&
+		old.Annotations[constants.FluentPVCBindingAnnotationActionRequestedBy] == b.Annotations[constants.FluentPVCBindingAnnotationActionRequestedBy] {
+		// NOTE: The action is already requested, and the request is updated for other reasons.
+		return admission.Allowed("")
+	}
+
+	logger.Info(fmt.Sprintf(
+		"Action='%s' is requested by '%s' for FluentPVCBinding='%s'(namespace='%s').",
+		action, req.UserInfo.Username, b.Name, req.Namespace,
+	))
+	b.Annotations[constants.FluentPVCBindingAnnotationActionRequestedBy] = req.UserInfo.Username
+	marshaled, err := json.Marshal(b)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+func (m *fluentPVCBindingMutator) InjectDecoder(d *admission.Decoder) error {
+	m.decoder = d
+	return nil
+}
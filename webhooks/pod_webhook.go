@@ -1,14 +1,23 @@
 package webhooks
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
 
+	"golang.org/x/xerrors"
+
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/rand"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -18,13 +27,24 @@ import (
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
 	"github.com/st-tech/fluent-pvc-operator/constants"
 	hashutils "github.com/st-tech/fluent-pvc-operator/utils/hash"
+	jobutils "github.com/st-tech/fluent-pvc-operator/utils/job"
 	podutils "github.com/st-tech/fluent-pvc-operator/utils/pod"
+	revisionutils "github.com/st-tech/fluent-pvc-operator/utils/revision"
 )
 
-//+kubebuilder:webhook:path=/pod/mutate,mutating=true,failurePolicy=fail,sideEffects=None,groups=core,resources=pods,verbs=create,versions=v1,name=pod-mutation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
+//+kubebuilder:webhook:path=/pod/mutate,mutating=true,failurePolicy=fail,sideEffects=NoneOnDryRun,groups=core,resources=pods,verbs=create,versions=v1,name=pod-mutation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
+//+kubebuilder:webhook:path=/pod/mutate-fail-open,mutating=true,failurePolicy=ignore,sideEffects=NoneOnDryRun,groups=core,resources=pods,verbs=create,versions=v1,name=pod-fail-open-mutation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
 //+kubebuilder:webhook:path=/pod/validate,mutating=false,failurePolicy=fail,sideEffects=None,groups=core,resources=pods,verbs=create,versions=v1,name=pod-validation-webhook.fluent-pvc-operator.tech.zozo.com,admissionReviewVersions={v1,v1beta1}
 
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcbindings/status,verbs=get;update
+//+kubebuilder:rbac:groups=fluent-pvc-operator.tech.zozo.com,resources=fluentpvcrevisions,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch
+
+// maxNameCollisionCount is the max number of the collision counts tried to find an unused name. The pods with
+// the same generateName try the names in the same order, so it must be larger than the number of the pods.
+const maxNameCollisionCount = 1000
 
 func PodAdmissionResponse(pod *corev1.Pod, req admission.Request) admission.Response {
 	marshaledPod, err := json.Marshal(pod)
@@ -35,22 +55,59 @@ func PodAdmissionResponse(pod *corev1.Pod, req admission.Request) admission.Resp
 }
 
 func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
+	// NOTE: The FluentPVCBindings are indexed by the FluentPVC name to count them on every admission. The index
+	//       of the owner FluentPVC is not used because it is registered by fluentPVCReconciler.
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&fluentpvcv1alpha1.FluentPVCBinding{},
+		constants.FluentPVCBindingFluentPVCNameField,
+		indexFluentPVCBindingByFluentPVCName,
+	); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
 	mgr.GetWebhookServer().Register("/pod/validate", &webhook.Admission{Handler: NewPodValidator(mgr.GetClient())})
 	mgr.GetWebhookServer().Register("/pod/mutate", &webhook.Admission{Handler: NewPodMutator(mgr.GetClient())})
+	mgr.GetWebhookServer().Register("/pod/mutate-fail-open", &webhook.Admission{Handler: NewFailOpenPodMutator(mgr.GetClient())})
+	mgr.GetWebhookServer().Register("/pod/ephemeralcontainers/mutate", &webhook.Admission{Handler: NewEphemeralContainersMutator(mgr.GetClient())})
 	return nil
 }
 
 type podMutator struct {
 	client.Client
 	decoder *admission.Decoder
+	// failOpenOnly is true if the mutator serves the webhook whose failurePolicy is Ignore. The webhook mutates
+	// only the pods labeled with the Ignore admission failure policy, and the other webhook mutates the others.
+	failOpenOnly bool
 }
 
 func NewPodMutator(c client.Client) admission.Handler {
 	return &podMutator{Client: c}
 }
 
+func NewFailOpenPodMutator(c client.Client) admission.Handler {
+	return &podMutator{Client: c, failOpenOnly: true}
+}
+
 func (m *podMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
-	logger := ctrl.LoggerFrom(ctx).WithName("podMutator").WithName("Handle")
+	pod := &corev1.Pod{}
+	if err := m.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// NOTE: Both webhooks are invoked if their objectSelectors are not configured, so each mutator skips the pods
+	//       that the other one mutates.
+	failOpen := pod.Labels[constants.PodLabelAdmissionFailurePolicy] == string(fluentpvcv1alpha1.FluentPVCAdmissionFailurePolicyIgnore)
+	if failOpen != m.failOpenOnly {
+		return admission.Allowed("")
+	}
+	resp := m.handle(ctx, req)
+	if resp.Allowed || resp.Result == nil || resp.Result.Code != http.StatusInternalServerError {
+		return resp
+	}
+	return m.failOpen(ctx, req, resp)
+}
+
+func (m *podMutator) handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrl.LoggerFrom(ctx).WithName("podMutator").WithName("handle")
 	pod := &corev1.Pod{}
 	if err := m.decoder.Decode(req, pod); err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
@@ -66,86 +123,738 @@ func (m *podMutator) Handle(ctx context.Context, req admission.Request) admissio
 			return admission.Errored(http.StatusInternalServerError, err)
 		}
 	}
+	if !isNamespaceAllowed(fpvc, req.Namespace) {
+		return admission.Denied(fmt.Sprintf("FluentPVC='%s' is not allowed in namespace='%s'.", fpvc.Name, req.Namespace))
+	}
 
-	// TODO: Consider too long fluent-pvc name
-	collisionCount := int32(rand.IntnRange(math.MinInt32, math.MaxInt32)) // Using the count for collision avoidance
-	name := fmt.Sprintf(
-		"%s-%s-%s",
-		fpvc.Name, hashutils.ComputeHash(fpvc, nil), hashutils.ComputeHash(pod, &collisionCount),
-	)
+	if b, err := m.findInjectedFluentPVCBinding(ctx, fpvc, pod, req.Namespace); err != nil {
+		logger.Error(err, fmt.Sprintf("Cannot find the injected FluentPVCBinding for Pod='%s'(namespace='%s').", pod.Name, req.Namespace))
+		return admission.Errored(http.StatusInternalServerError, err)
+	} else if b != nil {
+		// NOTE: The pod is admitted again (e.g. webhook reinvocation), so reuse the PVC and the FluentPVCBinding
+		//       instead of creating them again. The conflicts are ignored because they are with the items injected
+		//       by the previous admission.
+		podPatched, _, err := injectFluentPVC(pod, fpvc, b.Name, b.Spec.PVC.Name)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		logger.Info(fmt.Sprintf(
+			"Patch Pod='%s'(namespace='%s', generatorName='%s') with already injected PVC='%s' and FluentPVCBinding='%s' by FluentPVC='%s'.",
+			podPatched.Name, req.Namespace, podPatched.GenerateName, b.Spec.PVC.Name, b.Name, fpvc.Name,
+		))
+		return PodAdmissionResponse(podPatched, req)
+	}
 
-	logger.Info(fmt.Sprintf("Create PVC='%s'(namespace='%s').", name, req.Namespace))
-	pvc := &corev1.PersistentVolumeClaim{}
-	pvc.SetName(name)
-	pvc.SetNamespace(req.Namespace)
-	pvc.Spec = *fpvc.Spec.PVCSpecTemplate.DeepCopy()
-	controllerutil.AddFinalizer(pvc, constants.PVCFinalizerName)
-	// NOTE: fluentpvcbinding does not own pvc for preventing pvc from becoming terminating when fluentpvcbinding
-	//       is deleted. This is because the finalizer job cannot mount the pvc if it is terminating.
-	if err := m.Create(ctx, pvc, &client.CreateOptions{}); err != nil {
-		logger.Error(err, fmt.Sprintf("Cannot Create PVC='%s'(namespace='%s').", name, req.Namespace))
+	if b, err := m.findReattachableFluentPVCBinding(ctx, fpvc, pod, req.Namespace); err != nil {
+		logger.Error(err, fmt.Sprintf("Cannot find a reattachable FluentPVCBinding for Pod='%s'(namespace='%s').", pod.Name, req.Namespace))
 		return admission.Errored(http.StatusInternalServerError, err)
+	} else if b != nil {
+		podPatched, conflicts, err := injectFluentPVC(pod, fpvc, b.Name, b.Spec.PVC.Name)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if podutils.HasFailedConflict(conflicts) {
+			return deniedByConflicts(fpvc, conflicts)
+		}
+		logger.Info(fmt.Sprintf(
+			"Reattach PVC='%s' of FluentPVCBinding='%s' to Pod='%s'(namespace='%s').",
+			b.Spec.PVC.Name, b.Name, pod.Name, req.Namespace,
+		))
+		if !isDryRun(req) {
+			r, err := revisionutils.GetOrCreateFluentPVCRevision(ctx, m.Client, m.Scheme(), fpvc)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("Cannot get or create FluentPVCRevision for FluentPVC='%s'.", fpvc.Name))
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			if err := m.reattachFluentPVCBinding(ctx, b, pod, r); err != nil {
+				logger.Error(err, fmt.Sprintf("Cannot reattach FluentPVCBinding='%s' to Pod='%s'.", b.Name, pod.Name))
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+		}
+		logger.Info(fmt.Sprintf(
+			"Patch Pod='%s'(namespace='%s', generatorName='%s') with reattached PVC='%s' and FluentPVCBinding='%s' by FluentPVC='%s'.",
+			podPatched.Name, req.Namespace, podPatched.GenerateName, b.Spec.PVC.Name, b.Name, fpvc.Name,
+		))
+		return PodAdmissionResponse(podPatched, req).WithWarnings(conflictWarnings(conflicts)...)
 	}
 
-	logger.Info(fmt.Sprintf("Create FluentPVCBinding='%s'(namespace='%s').", name, req.Namespace))
-	b := &fluentpvcv1alpha1.FluentPVCBinding{}
-	b.SetName(name)
-	b.SetNamespace(req.Namespace)
-	b.SetFluentPVC(fpvc)
-	b.SetPod(pod)
-	b.SetPVC(pvc)
-	controllerutil.AddFinalizer(b, constants.FluentPVCBindingFinalizerName)
-	if err := ctrl.SetControllerReference(fpvc, b, m.Scheme()); err != nil {
-		logger.Error(err, fmt.Sprintf("Cannot set FluentPVC as a Controller OwnerReference on owned for FluentPVCBinding='%s'.", name))
+	if message, err := m.checkNamespaceLimits(ctx, fpvc, req.Namespace); err != nil {
+		logger.Error(err, fmt.Sprintf("Cannot check the limits of FluentPVC='%s' in namespace='%s'.", fpvc.Name, req.Namespace))
 		return admission.Errored(http.StatusInternalServerError, err)
+	} else if message != "" {
+		return admission.Denied(message)
 	}
-	if err := m.Create(ctx, b, &client.CreateOptions{}); err != nil {
-		logger.Error(err, fmt.Sprintf("Cannot Create FluentPVCBinding='%s'.", name))
+
+	if fpvc.Spec.ProvisioningMode == fluentpvcv1alpha1.FluentPVCProvisioningModeEphemeral {
+		// NOTE: The PVC is created by Kubernetes, and then pvcReconciler adopts it and creates the FluentPVCBinding.
+		podPatched, conflicts, err := injectEphemeralFluentPVC(pod, fpvc)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if podutils.HasFailedConflict(conflicts) {
+			return deniedByConflicts(fpvc, conflicts)
+		}
+		logger.Info(fmt.Sprintf(
+			"Patch Pod='%s'(namespace='%s', generatorName='%s') with an ephemeral volume by FluentPVC='%s'.",
+			podPatched.Name, req.Namespace, podPatched.GenerateName, fpvc.Name,
+		))
+		return PodAdmissionResponse(podPatched, req).WithWarnings(conflictWarnings(conflicts)...)
+	}
+	if fpvc.Spec.ProvisioningMode == fluentpvcv1alpha1.FluentPVCProvisioningModeController {
+		// NOTE: The PVC and the FluentPVCBinding are created by fluentPVCBindingReconciler after the pod is created,
+		//       so the name must be determined only by the admission request without any API writes.
+		n, injected := pod.Labels[constants.PodLabelFluentPVCBindingName]
+		name := n
+		if !injected {
+			// NOTE: The concurrent admissions of the pods with the same generateName may find the same unused
+			//       name because nothing is written here. fluentPVCBindingReconciler does not provision the
+			//       FluentPVCBinding for the pods sharing the name, so the pods have to be recreated.
+			computed, _, err := m.findUnusedFluentPVCBindingName(ctx, fpvc, pod, req.Namespace, 0)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("Cannot find an unused FluentPVCBinding name for Pod='%s'(namespace='%s').", pod.Name, req.Namespace))
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			name = computed
+		}
+		podPatched, conflicts, err := injectFluentPVC(pod, fpvc, name, name)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if injected {
+			// NOTE: The conflicts are with the items injected by the previous admission.
+			conflicts = nil
+		}
+		if podutils.HasFailedConflict(conflicts) {
+			return deniedByConflicts(fpvc, conflicts)
+		}
+		logger.Info(fmt.Sprintf(
+			"Patch Pod='%s'(namespace='%s', generatorName='%s') with PVC='%s' and FluentPVCBinding='%s' to be provisioned by FluentPVC='%s'.",
+			podPatched.Name, req.Namespace, podPatched.GenerateName, name, name, fpvc.Name,
+		))
+		return PodAdmissionResponse(podPatched, req).WithWarnings(conflictWarnings(conflicts)...)
+	}
+
+	name, collisionCount, err := m.findUnusedFluentPVCBindingName(ctx, fpvc, pod, req.Namespace, 0)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Cannot find an unused FluentPVCBinding name for Pod='%s'(namespace='%s').", pod.Name, req.Namespace))
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
-	b.SetPhasePending()
-	if err := m.Status().Update(ctx, b); err != nil {
-		logger.Error(err, fmt.Sprintf("Cannot update the status of FluentPVCBinding='%s'.", name))
+
+	// NOTE: Check the conflicts before creating any objects.
+	podPatched, conflicts, err := injectFluentPVC(pod, fpvc, name, name)
+	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
+	if podutils.HasFailedConflict(conflicts) {
+		return deniedByConflicts(fpvc, conflicts)
+	}
+
+	if isDryRun(req) {
+		// NOTE: Return the patched pod without creating any objects, so that dry-run requests have no side effects.
+		logger.Info(fmt.Sprintf(
+			"Patch Pod='%s'(namespace='%s', generatorName='%s') with PVC='%s' and FluentPVCBinding='%s' by FluentPVC='%s' in dry-run mode.",
+			podPatched.Name, req.Namespace, podPatched.GenerateName, name, name, fpvc.Name,
+		))
+		return PodAdmissionResponse(podPatched, req).WithWarnings(conflictWarnings(conflicts)...)
+	}
+
+	r, err := revisionutils.GetOrCreateFluentPVCRevision(ctx, m.Client, m.Scheme(), fpvc)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Cannot get or create FluentPVCRevision for FluentPVC='%s'.", fpvc.Name))
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	var b *fluentpvcv1alpha1.FluentPVCBinding
+	var pvc *corev1.PersistentVolumeClaim
+	for {
+		b, pvc, err = m.createFluentPVCBinding(ctx, fpvc, r, pod, req.Namespace, name)
+		if err == nil {
+			break
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			logger.Error(err, fmt.Sprintf("Cannot create FluentPVCBinding='%s'(namespace='%s').", name, req.Namespace))
+			if isQuotaExceeded(err) {
+				return deniedByQuota(fpvc, req.Namespace, err)
+			}
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		// NOTE: The name is taken by another admission after it is found unused, so try the next collision count.
+		logger.Info(fmt.Sprintf("FluentPVCBinding='%s'(namespace='%s') is already taken, so compute another name.", name, req.Namespace))
+		name, collisionCount, err = m.findUnusedFluentPVCBindingName(ctx, fpvc, pod, req.Namespace, collisionCount+1)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Cannot find an unused FluentPVCBinding name for Pod='%s'(namespace='%s').", pod.Name, req.Namespace))
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		podPatched, conflicts, err = injectFluentPVC(pod, fpvc, name, name)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
 
 	logger.Info(fmt.Sprintf(
 		"Inject PVC='%s' into Pod='%s'(namespace='%s', generatorName='%s').",
 		name, pod.Name, req.Namespace, pod.GenerateName,
 	))
-	podPatched := pod.DeepCopy()
+
+	logger.Info(fmt.Sprintf(
+		"Patch Pod='%s'(namespace='%s', generatorName='%s') with PVC='%s' and FluentPVCBinding='%s' by FluentPVC='%s'.",
+		podPatched.Name, req.Namespace, podPatched.GenerateName, pvc.Name, b.Name, fpvc.Name,
+	))
+	return PodAdmissionResponse(podPatched, req).WithWarnings(conflictWarnings(conflicts)...)
+}
+
+// failOpen admits the pod that cannot be mutated because of the errors if the admissionFailurePolicy of
+// the FluentPVC is "Ignore". The policy is read from the pod label if the FluentPVC cannot be read.
+func (m *podMutator) failOpen(ctx context.Context, req admission.Request, resp admission.Response) admission.Response {
+	logger := ctrl.LoggerFrom(ctx).WithName("podMutator").WithName("failOpen")
+	pod := &corev1.Pod{}
+	if err := m.decoder.Decode(req, pod); err != nil {
+		return resp
+	}
+	fpvc := &fluentpvcv1alpha1.FluentPVC{}
+	if err := m.Get(ctx, client.ObjectKey{Name: pod.Labels[constants.PodLabelFluentPVCName]}, fpvc); err != nil {
+		if pod.Labels[constants.PodLabelAdmissionFailurePolicy] != string(fluentpvcv1alpha1.FluentPVCAdmissionFailurePolicyIgnore) {
+			return resp
+		}
+		fpvc = nil
+	} else if fpvc.Spec.AdmissionFailurePolicy != fluentpvcv1alpha1.FluentPVCAdmissionFailurePolicyIgnore {
+		return resp
+	}
+
+	degraded := "EmptyDir"
+	var podPatched *corev1.Pod
+	if fpvc != nil {
+		patched, conflicts, err := injectFluentPVCVolume(pod, fpvc, corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}})
+		if err == nil && !podutils.HasFailedConflict(conflicts) {
+			podPatched = patched
+		}
+	}
+	if podPatched == nil {
+		degraded = "Unmodified"
+		podPatched = pod.DeepCopy()
+	}
 	if podPatched.Labels == nil {
 		podPatched.Labels = map[string]string{}
 	}
-	podPatched.Labels[constants.PodLabelFluentPVCBindingName] = name
-	for _, v := range fpvc.Spec.CommonVolumes {
-		podutils.InjectOrReplaceVolume(&podPatched.Spec, v.DeepCopy())
+	podPatched.Labels[constants.PodLabelDegraded] = degraded
+	logger.Info(fmt.Sprintf(
+		"Admit Pod='%s'(namespace='%s', generatorName='%s') as degraded='%s' because the admissionFailurePolicy is Ignore.: %s",
+		pod.Name, req.Namespace, pod.GenerateName, degraded, resp.Result.Message,
+	))
+	return PodAdmissionResponse(podPatched, req).WithWarnings(fmt.Sprintf(
+		"fluent-pvc-operator: The pod is admitted as degraded='%s' without the PVC because of the error: %s",
+		degraded, resp.Result.Message,
+	))
+}
+
+func deniedByConflicts(fpvc *fluentpvcv1alpha1.FluentPVC, conflicts []podutils.Conflict) admission.Response {
+	messages := []string{}
+	for _, c := range conflicts {
+		if c.Strategy == podutils.MergeStrategyFail {
+			messages = append(messages, c.String())
+		}
+	}
+	return admission.Denied(fmt.Sprintf(
+		"Cannot inject FluentPVC='%s' into the pod because of the conflicts: %s",
+		fpvc.Name, strings.Join(messages, " "),
+	))
+}
+
+// deniedByQuota denies the pod clearly instead of returning the internal error, because the pod creation is
+// retried in a tight loop by its controller until the quota is available.
+func deniedByQuota(fpvc *fluentpvcv1alpha1.FluentPVC, namespace string, err error) admission.Response {
+	return admission.Denied(fmt.Sprintf(
+		"Cannot provision the PVC of FluentPVC='%s' because the ResourceQuota of namespace='%s' is exceeded.: %s",
+		fpvc.Name, namespace, err.Error(),
+	))
+}
+
+// isQuotaExceeded returns true if the error is returned by the ResourceQuota admission plugin of kube-apiserver.
+// The other Forbidden errors (e.g. RBAC and Pod Security admission) are returned as the internal error.
+func isQuotaExceeded(err error) bool {
+	var status apierrors.APIStatus
+	if !xerrors.As(err, &status) || status.Status().Reason != metav1.StatusReasonForbidden {
+		return false
+	}
+	// NOTE: The plugin returns a plain Forbidden status without any reasons or causes specific to quotas
+	//       (see k8s.io/apiserver/pkg/admission/plugin/resourcequota), so its message is matched.
+	return strings.Contains(status.Status().Message, "exceeded quota")
+}
+
+func conflictWarnings(conflicts []podutils.Conflict) []string {
+	warnings := []string{}
+	for _, c := range conflicts {
+		warnings = append(warnings, fmt.Sprintf("fluent-pvc-operator: %s", c.String()))
 	}
-	podutils.InjectOrReplaceVolume(&podPatched.Spec, &corev1.Volume{
-		Name: fpvc.Spec.PVCVolumeName,
-		VolumeSource: corev1.VolumeSource{
-			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-				ClaimName: name,
+	return warnings
+}
+
+type nameTemplateData struct {
+	FluentPVCName   string
+	PodName         string
+	PodGenerateName string
+	Namespace       string
+}
+
+// computeFluentPVCBindingName returns the name for the PVC, the FluentPVCBinding and the finalizer Job.
+// The name is computed only from the FluentPVC spec, the namespace, the pod name or generateName and the
+// collisionCount, so that a dry-run request and the following request compute the same name. The pods with
+// the same generateName share the name of each collisionCount, so see findUnusedFluentPVCBindingName.
+func computeFluentPVCBindingName(fpvc *fluentpvcv1alpha1.FluentPVC, pod *corev1.Pod, namespace string, collisionCount int32) (string, error) {
+	prefix := fpvc.Name
+	if fpvc.Spec.NameTemplate != "" {
+		p, err := renderNameTemplate(fpvc.Spec.NameTemplate, &nameTemplateData{
+			FluentPVCName:   fpvc.Name,
+			PodName:         pod.Name,
+			PodGenerateName: pod.GenerateName,
+			Namespace:       namespace,
+		})
+		if err != nil {
+			return "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		prefix = p
+	}
+	podHash := hashutils.ComputeHash([]string{namespace, pod.Name, pod.GenerateName}, &collisionCount)
+	return hashutils.ComputeName(prefix, hashutils.ComputeHash(&fpvc.Spec, nil), podHash), nil
+}
+
+func renderNameTemplate(nameTemplate string, data *nameTemplateData) (string, error) {
+	t, err := template.New("nameTemplate").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", xerrors.Errorf("Cannot parse the name template.: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return "", xerrors.Errorf("Cannot render the name template.: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func injectFluentPVC(pod *corev1.Pod, fpvc *fluentpvcv1alpha1.FluentPVC, bindingName, claimName string) (*corev1.Pod, []podutils.Conflict, error) {
+	podPatched, conflicts, err := injectFluentPVCVolume(pod, fpvc, corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: claimName,
+		},
+	})
+	if err != nil {
+		return nil, nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	podPatched.Labels[constants.PodLabelFluentPVCBindingName] = bindingName
+	return podPatched, conflicts, nil
+}
+
+// injectFluentPVCByProvisioningMode injects the FluentPVC into a new pod in the same way as podMutator
+// does according to the provisioning mode. name is used for the FluentPVCBinding and the PVC.
+func injectFluentPVCByProvisioningMode(pod *corev1.Pod, fpvc *fluentpvcv1alpha1.FluentPVC, name string) (*corev1.Pod, []podutils.Conflict, error) {
+	if fpvc.Spec.ProvisioningMode == fluentpvcv1alpha1.FluentPVCProvisioningModeEphemeral {
+		return injectEphemeralFluentPVC(pod, fpvc)
+	}
+	return injectFluentPVC(pod, fpvc, name, name)
+}
+
+// injectEphemeralFluentPVC injects a generic ephemeral volume instead of a PVC. The name of the PVC is
+// determined by Kubernetes after the pod is created, so the pod does not have the FluentPVCBinding label.
+func injectEphemeralFluentPVC(pod *corev1.Pod, fpvc *fluentpvcv1alpha1.FluentPVC) (*corev1.Pod, []podutils.Conflict, error) {
+	return injectFluentPVCVolume(pod, fpvc, corev1.VolumeSource{
+		Ephemeral: &corev1.EphemeralVolumeSource{
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+				Spec: *fpvc.Spec.PVCSpecTemplate.DeepCopy(),
 			},
 		},
 	})
-	podutils.InjectOrReplaceContainer(&podPatched.Spec, fpvc.Spec.SidecarContainerTemplate.DeepCopy())
+}
+
+// injectFluentPVCVolume returns the pod injected with the volume and the sidecar container according to
+// the merge strategies of the FluentPVC, and the conflicts with the items of the pod.
+func injectFluentPVCVolume(pod *corev1.Pod, fpvc *fluentpvcv1alpha1.FluentPVC, source corev1.VolumeSource) (*corev1.Pod, []podutils.Conflict, error) {
+	selector, err := podutils.NewContainerSelector(
+		fpvc.Spec.TargetContainers.Names,
+		fpvc.Spec.TargetContainers.NameRegex,
+		fpvc.Spec.TargetContainers.ExcludeNames,
+		fpvc.Spec.SidecarContainerTemplate.Name,
+	)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("FluentPVC.spec.targetContainers.nameRegex is invalid.: %w", err)
+	}
+	podPatched := pod.DeepCopy()
+	if podPatched.Labels == nil {
+		podPatched.Labels = map[string]string{}
+	}
+	injector := podutils.NewInjector(&podPatched.Spec, podutils.MergeStrategies{
+		Containers:   podutils.MergeStrategy(fpvc.Spec.MergeStrategies.Containers),
+		Volumes:      podutils.MergeStrategy(fpvc.Spec.MergeStrategies.Volumes),
+		VolumeMounts: podutils.MergeStrategy(fpvc.Spec.MergeStrategies.VolumeMounts),
+		Envs:         podutils.MergeStrategy(fpvc.Spec.MergeStrategies.Envs),
+		PodTemplate:  podutils.MergeStrategy(fpvc.Spec.MergeStrategies.PodTemplateOverrides),
+	}).WithContainerSelector(selector)
+	if fpvc.Spec.InjectInitContainers {
+		injector.WithInitContainers()
+	}
+	for _, v := range fpvc.Spec.CommonVolumes {
+		injector.InjectVolume(v.DeepCopy())
+	}
+	injector.InjectVolume(&corev1.Volume{
+		Name:         fpvc.Spec.PVCVolumeName,
+		VolumeSource: source,
+	})
+	injector.InjectContainer(fpvc.Spec.SidecarContainerTemplate.DeepCopy())
 	for _, vm := range fpvc.Spec.CommonVolumeMounts {
-		podutils.InjectOrReplaceVolumeMount(&podPatched.Spec, vm.DeepCopy())
+		injector.InjectVolumeMount(vm.DeepCopy())
 	}
-	podutils.InjectOrReplaceVolumeMount(&podPatched.Spec, &corev1.VolumeMount{
+	injector.InjectVolumeMountWithSubPaths(&corev1.VolumeMount{
 		Name:      fpvc.Spec.PVCVolumeName,
 		MountPath: fpvc.Spec.PVCVolumeMountPath,
-	})
+	}, fpvc.Spec.PVCVolumeMountSubPaths)
 	for _, e := range fpvc.Spec.CommonEnvs {
-		podutils.InjectOrReplaceEnv(&podPatched.Spec, e.DeepCopy())
+		injector.InjectEnv(e.DeepCopy())
 	}
+	injectPodTemplateOverrides(injector, podPatched, &fpvc.Spec.PodTemplateOverrides)
+	return podPatched, injector.Conflicts(), nil
+}
 
-	logger.Info(fmt.Sprintf(
-		"Patch Pod='%s'(namespace='%s', generatorName='%s') with PVC='%s' and FluentPVCBinding='%s' by FluentPVC='%s'.",
-		podPatched.Name, req.Namespace, podPatched.GenerateName, pvc.Name, b.Name, fpvc.Name,
+// injectPodTemplateOverrides merges the pod-level overrides into the pod.
+func injectPodTemplateOverrides(injector *podutils.Injector, pod *corev1.Pod, overrides *fluentpvcv1alpha1.FluentPVCPodTemplateOverrides) {
+	injector.InjectLabels(&pod.ObjectMeta, overrides.Metadata.Labels)
+	injector.InjectAnnotations(&pod.ObjectMeta, overrides.Metadata.Annotations)
+	injector.InjectSecurityContext(overrides.SecurityContext)
+	for _, t := range overrides.Tolerations {
+		injector.InjectToleration(t.DeepCopy())
+	}
+	names := []string{}
+	for n := range overrides.ContainerResources {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		r := overrides.ContainerResources[n]
+		injector.InjectResources(n, &r)
+	}
+}
+
+// findInjectedFluentPVCBinding returns the FluentPVCBinding that is already injected into the pod.
+// nil is returned if the pod is admitted for the first time.
+func (m *podMutator) findInjectedFluentPVCBinding(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	pod *corev1.Pod,
+	namespace string,
+) (*fluentpvcv1alpha1.FluentPVCBinding, error) {
+	name, ok := pod.Labels[constants.PodLabelFluentPVCBindingName]
+	if !ok {
+		return nil, nil
+	}
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	if err := m.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, b); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if b.Spec.FluentPVC.Name != fpvc.Name || b.Spec.Pod.Name != pod.Name || !b.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// createFluentPVCBinding creates the PVC and the FluentPVCBinding named name for the pod. The error of
+// AlreadyExists is returned as it is if the name is already taken, so that the caller can try another name.
+func (m *podMutator) createFluentPVCBinding(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	r *fluentpvcv1alpha1.FluentPVCRevision,
+	pod *corev1.Pod,
+	namespace, name string,
+) (*fluentpvcv1alpha1.FluentPVCBinding, *corev1.PersistentVolumeClaim, error) {
+	logger := ctrl.LoggerFrom(ctx).WithName("podMutator").WithName("createFluentPVCBinding")
+	b := &fluentpvcv1alpha1.FluentPVCBinding{}
+	b.SetName(name)
+	b.SetNamespace(namespace)
+	b.SetFluentPVC(fpvc)
+	b.SetFluentPVCRevision(r)
+	b.SetPod(pod)
+	controllerutil.AddFinalizer(b, constants.FluentPVCBindingFinalizerName)
+	if err := ctrl.SetControllerReference(fpvc, b, m.Scheme()); err != nil {
+		return nil, nil, xerrors.Errorf("Cannot set FluentPVC as a Controller OwnerReference on owned for FluentPVCBinding='%s'.: %w", name, err)
+	}
+
+	logger.Info(fmt.Sprintf("Create PVC='%s'(namespace='%s').", name, namespace))
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvc.SetName(name)
+	pvc.SetNamespace(namespace)
+	pvc.Spec = *fpvc.Spec.PVCSpecTemplate.DeepCopy()
+	controllerutil.AddFinalizer(pvc, constants.PVCFinalizerName)
+	// NOTE: fluentpvcbinding does not own pvc for preventing pvc from becoming terminating when fluentpvcbinding
+	//       is deleted. This is because the finalizer job cannot mount the pvc if it is terminating.
+	if err := m.Create(ctx, pvc, &client.CreateOptions{}); err != nil {
+		return nil, nil, xerrors.Errorf("Cannot Create PVC='%s'(namespace='%s').: %w", name, namespace, err)
+	}
+
+	// NOTE: The PVC and the FluentPVCBinding are created by separate API calls, so delete the created objects
+	//       when the later steps fail. Objects that cannot be deleted here are collected by the pvc collector.
+	logger.Info(fmt.Sprintf("Create FluentPVCBinding='%s'(namespace='%s').", name, namespace))
+	b.SetPVC(pvc)
+	if err := m.Create(ctx, b, &client.CreateOptions{}); err != nil {
+		m.rollbackPVC(ctx, pvc)
+		return nil, nil, xerrors.Errorf("Cannot Create FluentPVCBinding='%s'.: %w", name, err)
+	}
+	b.SetPhasePending()
+	if err := m.Status().Update(ctx, b); err != nil {
+		m.rollbackFluentPVCBinding(ctx, b)
+		m.rollbackPVC(ctx, pvc)
+		return nil, nil, xerrors.Errorf("Cannot update the status of FluentPVCBinding='%s'.: %w", name, err)
+	}
+	return b, pvc, nil
+}
+
+// findUnusedFluentPVCBindingName returns the first name that is not used by any PVCs, FluentPVCBindings and
+// pods in the namespace, trying the collision counts from collisionCount in order. The found collision count
+// is also returned to resume the search when the name is taken before it is created.
+func (m *podMutator) findUnusedFluentPVCBindingName(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	pod *corev1.Pod,
+	namespace string,
+	collisionCount int32,
+) (string, int32, error) {
+	for ; collisionCount < maxNameCollisionCount; collisionCount++ {
+		name, err := computeFluentPVCBindingName(fpvc, pod, namespace, collisionCount)
+		if err != nil {
+			return "", 0, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		used, err := m.isFluentPVCBindingNameUsed(ctx, namespace, name)
+		if err != nil {
+			return "", 0, xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		if !used {
+			return name, collisionCount, nil
+		}
+	}
+	return "", 0, xerrors.New(fmt.Sprintf(
+		"Cannot find an unused name for FluentPVC='%s' in namespace='%s' within %d collisions.",
+		fpvc.Name, namespace, maxNameCollisionCount,
 	))
-	return PodAdmissionResponse(podPatched, req)
+}
+
+func (m *podMutator) isFluentPVCBindingNameUsed(ctx context.Context, namespace, name string) (bool, error) {
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+	if err := m.Get(ctx, key, &fluentpvcv1alpha1.FluentPVCBinding{}); err == nil {
+		return true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if err := m.Get(ctx, key, &corev1.PersistentVolumeClaim{}); err == nil {
+		return true, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	// NOTE: The pods in Controller mode have the name before the PVC and the FluentPVCBinding are created.
+	pods := &corev1.PodList{}
+	if err := m.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels{
+		constants.PodLabelFluentPVCBindingName: name,
+	}); err != nil {
+		return false, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return len(pods.Items) != 0, nil
+}
+
+func (m *podMutator) rollbackPVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim) {
+	logger := ctrl.LoggerFrom(ctx).WithName("podMutator").WithName("rollbackPVC")
+	logger.Info(fmt.Sprintf("Delete PVC='%s'(namespace='%s') because the admission is failed.", pvc.Name, pvc.Namespace))
+	controllerutil.RemoveFinalizer(pvc, constants.PVCFinalizerName)
+	if err := m.Update(ctx, pvc); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, fmt.Sprintf("Cannot remove the finalizer from PVC='%s'(namespace='%s').", pvc.Name, pvc.Namespace))
+		return
+	}
+	if err := m.Delete(ctx, pvc); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, fmt.Sprintf("Cannot delete PVC='%s'(namespace='%s').", pvc.Name, pvc.Namespace))
+	}
+}
+
+func (m *podMutator) rollbackFluentPVCBinding(ctx context.Context, b *fluentpvcv1alpha1.FluentPVCBinding) {
+	logger := ctrl.LoggerFrom(ctx).WithName("podMutator").WithName("rollbackFluentPVCBinding")
+	logger.Info(fmt.Sprintf("Delete FluentPVCBinding='%s'(namespace='%s') because the admission is failed.", b.Name, b.Namespace))
+	controllerutil.RemoveFinalizer(b, constants.FluentPVCBindingFinalizerName)
+	if err := m.Update(ctx, b); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, fmt.Sprintf("Cannot remove the finalizer from FluentPVCBinding='%s'(namespace='%s').", b.Name, b.Namespace))
+		return
+	}
+	if err := m.Delete(ctx, b); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, fmt.Sprintf("Cannot delete FluentPVCBinding='%s'(namespace='%s').", b.Name, b.Namespace))
+	}
+}
+
+// checkNamespaceLimits returns the message to deny the pod if a new PVC exceeds maxPVCsPerNamespace or
+// maxTotalStoragePerNamespace of the FluentPVC, or the empty string otherwise. The limits are best-effort: the
+// counts are read from the cache without any locks, so the concurrent admissions in the same namespace can pass
+// the check at the same time and exceed the limits slightly. ResourceQuotas are the hard limits.
+func (m *podMutator) checkNamespaceLimits(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	namespace string,
+) (string, error) {
+	if fpvc.Spec.MaxPVCsPerNamespace == nil && fpvc.Spec.MaxTotalStoragePerNamespace == nil {
+		return "", nil
+	}
+	bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+	if err := m.List(
+		ctx,
+		bindings,
+		client.InNamespace(namespace),
+		client.MatchingFields{constants.FluentPVCBindingFluentPVCNameField: fpvc.Name},
+	); err != nil {
+		return "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+
+	// NOTE: The fluentpvcbindings are live while their pvcs exist, including the finalizer pvcs restored from
+	//       the snapshots. The storage is counted by the pvcs to include the ones resized after provisioning.
+	count := int32(0)
+	total := resource.Quantity{}
+	for _, b := range bindings.Items {
+		pvcName := b.Spec.PVC.Name
+		if b.Status.FinalizerPVC != nil {
+			pvcName = b.Status.FinalizerPVC.Name
+		}
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := m.Get(ctx, client.ObjectKey{Namespace: namespace, Name: pvcName}, pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", xerrors.Errorf("Unexpected error occurred.: %w", err)
+		}
+		count++
+		total.Add(*pvc.Spec.Resources.Requests.Storage())
+	}
+
+	if limit := fpvc.Spec.MaxPVCsPerNamespace; limit != nil && count >= *limit {
+		return fmt.Sprintf(
+			"Cannot provision a new PVC of FluentPVC='%s' because namespace='%s' already has %d PVCs, which reaches maxPVCsPerNamespace=%d.",
+			fpvc.Name, namespace, count, *limit,
+		), nil
+	}
+	if limit := fpvc.Spec.MaxTotalStoragePerNamespace; limit != nil {
+		total.Add(*fpvc.Spec.PVCSpecTemplate.Resources.Requests.Storage())
+		if total.Cmp(*limit) > 0 {
+			return fmt.Sprintf(
+				"Cannot provision a new PVC of FluentPVC='%s' because the total storage of the PVCs in namespace='%s' becomes %s, which exceeds maxTotalStoragePerNamespace=%s.",
+				fpvc.Name, namespace, total.String(), limit.String(),
+			), nil
+		}
+	}
+	return "", nil
+}
+
+// findReattachableFluentPVCBinding returns the newest FluentPVCBinding that was bound to the predecessor
+// of the pod, is out of use and whose finalizer job is not applied yet. nil is returned if there is none.
+func (m *podMutator) findReattachableFluentPVCBinding(
+	ctx context.Context,
+	fpvc *fluentpvcv1alpha1.FluentPVC,
+	pod *corev1.Pod,
+	namespace string,
+) (*fluentpvcv1alpha1.FluentPVCBinding, error) {
+	if fpvc.Spec.ReattachPolicy != fluentpvcv1alpha1.FluentPVCReattachPolicyStatefulSetOrdinal {
+		return nil, nil
+	}
+	if !isStatefulSetPod(pod) {
+		return nil, nil
+	}
+	bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+	if err := m.List(ctx, bindings, client.InNamespace(namespace)); err != nil {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	var found *fluentpvcv1alpha1.FluentPVCBinding
+	for i := range bindings.Items {
+		b := &bindings.Items[i]
+		if b.Spec.FluentPVC.Name != fpvc.Name || b.Spec.Pod.Name != pod.Name {
+			continue
+		}
+		if !b.DeletionTimestamp.IsZero() || !b.IsConditionOutOfUse() || b.IsConditionUnknown() ||
+			b.IsConditionPodMissing() || b.IsConditionFinalizerJobApplied() || b.Status.FinalizerPVC != nil {
+			continue
+		}
+		if found != nil && !found.CreationTimestamp.Before(&b.CreationTimestamp) {
+			continue
+		}
+		found = b
+	}
+	if found == nil {
+		return nil, nil
+	}
+
+	j := &batchv1.Job{}
+	if err := m.Get(ctx, client.ObjectKey{Namespace: namespace, Name: jobutils.FinalizerJobName(found)}, j); err == nil {
+		// NOTE: The finalizer job is already applied, but the status of the fluentpvcbinding is not updated yet.
+		return nil, nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := m.Get(ctx, client.ObjectKey{Namespace: namespace, Name: found.Spec.PVC.Name}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	if !found.IsBindingPVC(pvc) || !pvc.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	return found, nil
+}
+
+func (m *podMutator) reattachFluentPVCBinding(
+	ctx context.Context,
+	b *fluentpvcv1alpha1.FluentPVCBinding,
+	pod *corev1.Pod,
+	r *fluentpvcv1alpha1.FluentPVCRevision,
+) error {
+	// NOTE: Update the status before the spec so that pvcReconciler does not apply the finalizer job and
+	//       fluentPVCBindingReconciler waits for the new pod without regarding it as missing.
+	message := fmt.Sprintf(
+		"pvc='%s'(UID='%s') is reattached to pod='%s' from pod(UID='%s').(fluentpvcbinding='%s')",
+		b.Spec.PVC.Name, b.Spec.PVC.UID, pod.Name, b.Spec.Pod.UID, b.Name,
+	)
+	b.SetConditionNotOutOfUse("PodReattached", message)
+	b.SetConditionNotReady("PodReattached", message)
+	if err := m.Status().Update(ctx, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	// NOTE: The pod does not have a UID when the webhook is invoked, so fluentPVCBindingReconciler fills it.
+	b.SetPod(pod)
+	// NOTE: The reattached pod writes data to the pvc with the current spec, so finalize the pvc with it.
+	b.SetFluentPVCRevision(r)
+	if err := m.Update(ctx, b); err != nil {
+		return xerrors.Errorf("Unexpected error occurred.: %w", err)
+	}
+	return nil
+}
+
+func isNamespaceAllowed(fpvc *fluentpvcv1alpha1.FluentPVC, namespace string) bool {
+	if len(fpvc.Spec.AllowedNamespaces) == 0 {
+		return true
+	}
+	for _, ns := range fpvc.Spec.AllowedNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func isDryRun(req admission.Request) bool {
+	return req.DryRun != nil && *req.DryRun
+}
+
+func isStatefulSetPod(pod *corev1.Pod) bool {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "StatefulSet" || pod.Name == "" {
+		return false
+	}
+	// NOTE: Pods of StatefulSets are named '<statefulset name>-<ordinal>'.
+	prefix := owner.Name + "-"
+	if !strings.HasPrefix(pod.Name, prefix) {
+		return false
+	}
+	_, err := strconv.Atoi(strings.TrimPrefix(pod.Name, prefix))
+	return err == nil
 }
 
 func (m *podMutator) InjectDecoder(d *admission.Decoder) error {
@@ -187,3 +896,8 @@ func (v *podValidator) InjectDecoder(d *admission.Decoder) error {
 	v.decoder = d
 	return nil
 }
+
+func indexFluentPVCBindingByFluentPVCName(obj client.Object) []string {
+	b := obj.(*fluentpvcv1alpha1.FluentPVCBinding)
+	return []string{b.Spec.FluentPVC.Name}
+}
@@ -2,9 +2,12 @@ package webhooks
 
 import (
 	"context"
+	"strings"
+	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/xerrors"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -12,7 +15,13 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	fluentpvcv1alpha1 "github.com/st-tech/fluent-pvc-operator/api/v1alpha1"
 	"github.com/st-tech/fluent-pvc-operator/constants"
@@ -76,7 +85,7 @@ var _ = Describe("Pod Mutation Webhook", func() {
 					Command: []string{"echo", "test"},
 					Image:   "alpine",
 				},
-				DeletePodIfSidecarContainerTerminationDetected: true,
+				DeletePodIfSidecarContainerTerminationDetected: pointer.BoolPtr(true),
 				PVCFinalizerJobSpecTemplate: batchv1.JobSpec{
 					Template: corev1.PodTemplateSpec{
 						Spec: corev1.PodSpec{
@@ -186,6 +195,541 @@ var _ = Describe("Pod Mutation Webhook", func() {
 		}
 
 	})
+	It("should record the FluentPVCRevision of the current FluentPVC spec in the FluentPVCBinding.", func() {
+		ctx := context.Background()
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+
+			b := &fluentpvcv1alpha1.FluentPVCBinding{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: mutPod.Labels[constants.PodLabelFluentPVCBindingName]}, b)
+			Expect(err).Should(Succeed())
+			Expect(b.Spec.FluentPVCRevision).NotTo(BeNil())
+
+			fpvc := &fluentpvcv1alpha1.FluentPVC{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			rev := &fluentpvcv1alpha1.FluentPVCRevision{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: b.Spec.FluentPVCRevision.Name}, rev)
+			Expect(err).Should(Succeed())
+			Expect(rev.UID).Should(BeEquivalentTo(b.Spec.FluentPVCRevision.UID))
+			Expect(rev.Revision).Should(BeEquivalentTo(1))
+			Expect(rev.Spec.SidecarContainerTemplate.Name).Should(BeEquivalentTo(testSidecarContainerName))
+			Expect(metav1.IsControlledBy(rev, fpvc)).Should(BeTrue())
+		}
+	})
+	It("should reattach the PVC of the predecessor pod when the reattach policy is StatefulSetOrdinal.", func() {
+		const (
+			testStatefulSetName = "test-sts"
+			testReattachName    = "test-reattach"
+		)
+		ctx := context.Background()
+		{
+			fpvc := &fluentpvcv1alpha1.FluentPVC{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.ReattachPolicy = fluentpvcv1alpha1.FluentPVCReattachPolicyStatefulSetOrdinal
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pvc := &corev1.PersistentVolumeClaim{}
+		pvc.SetName(testReattachName)
+		pvc.SetNamespace(testNamespace)
+		pvc.Spec = *testFluentPVC.Spec.PVCSpecTemplate.DeepCopy()
+		{
+			err := k8sClient.Create(ctx, pvc)
+			Expect(err).Should(Succeed())
+		}
+		b := &fluentpvcv1alpha1.FluentPVCBinding{}
+		b.SetName(testReattachName)
+		b.SetNamespace(testNamespace)
+		b.SetFluentPVC(testFluentPVC)
+		b.SetPVC(pvc)
+		b.Spec.Pod = fluentpvcv1alpha1.ObjectIdentity{Name: testStatefulSetName + "-0", UID: "predecessor-uid"}
+		{
+			err := k8sClient.Create(ctx, b)
+			Expect(err).Should(Succeed())
+			b.SetConditionReady("PodFoundPVCFound", "")
+			b.SetConditionOutOfUse("PodDeletedPVCFound", "")
+			err = k8sClient.Status().Update(ctx, b)
+			Expect(err).Should(Succeed())
+		}
+		defer func() {
+			err := k8sClient.Delete(ctx, b)
+			Expect(client.IgnoreNotFound(err)).Should(Succeed())
+			err = k8sClient.Delete(ctx, pvc)
+			Expect(client.IgnoreNotFound(err)).Should(Succeed())
+		}()
+
+		pod := testPod.DeepCopy()
+		pod.SetName(testStatefulSetName + "-0")
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		pod.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "StatefulSet",
+			Name:       testStatefulSetName,
+			UID:        "statefulset-uid",
+			Controller: func(b bool) *bool { return &b }(true),
+		}})
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		defer func() {
+			err := k8sClient.Delete(ctx, pod)
+			Expect(client.IgnoreNotFound(err)).Should(Succeed())
+		}()
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+			var volumeForPVC *corev1.Volume
+			for _, v := range mutPod.Spec.Volumes {
+				if v.PersistentVolumeClaim != nil && v.Name == testVolumeName {
+					volumeForPVC = v.DeepCopy()
+				}
+			}
+			Expect(volumeForPVC).NotTo(BeNil())
+			Expect(volumeForPVC.PersistentVolumeClaim.ClaimName).Should(BeEquivalentTo(testReattachName))
+			Expect(mutPod.Labels[constants.PodLabelFluentPVCBindingName]).Should(BeEquivalentTo(testReattachName))
+
+			reattached := &fluentpvcv1alpha1.FluentPVCBinding{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: testReattachName}, reattached)
+			Expect(err).Should(Succeed())
+			Expect(reattached.Spec.Pod.Name).Should(BeEquivalentTo(pod.Name))
+			Expect(reattached.Spec.Pod.UID).Should(BeEmpty())
+			Expect(reattached.IsConditionOutOfUse()).Should(BeFalse())
+		}
+	})
+	It("should patch the Pod without creating any objects when the request is dry-run.", func() {
+		ctx := context.Background()
+		pvcs := &corev1.PersistentVolumeClaimList{}
+		err := k8sClient.List(ctx, pvcs, client.InNamespace(testNamespace))
+		Expect(err).Should(Succeed())
+		bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+		err = k8sClient.List(ctx, bindings, client.InNamespace(testNamespace))
+		Expect(err).Should(Succeed())
+
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		err = k8sClient.Create(ctx, pod, client.DryRunAll)
+		Expect(err).Should(Succeed())
+
+		var volumeForPVC *corev1.Volume
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.Name == testVolumeName {
+				volumeForPVC = v.DeepCopy()
+			}
+		}
+		Expect(volumeForPVC).NotTo(BeNil())
+		Expect(pod.Labels[constants.PodLabelFluentPVCBindingName]).Should(BeEquivalentTo(volumeForPVC.PersistentVolumeClaim.ClaimName))
+
+		pvcsAfter := &corev1.PersistentVolumeClaimList{}
+		err = k8sClient.List(ctx, pvcsAfter, client.InNamespace(testNamespace))
+		Expect(err).Should(Succeed())
+		Expect(pvcsAfter.Items).Should(HaveLen(len(pvcs.Items)))
+		bindingsAfter := &fluentpvcv1alpha1.FluentPVCBindingList{}
+		err = k8sClient.List(ctx, bindingsAfter, client.InNamespace(testNamespace))
+		Expect(err).Should(Succeed())
+		Expect(bindingsAfter.Items).Should(HaveLen(len(bindings.Items)))
+	})
+	It("should create a PVC whose name is a DNS label even if the FluentPVC name is long.", func() {
+		ctx := context.Background()
+		fpvc := testFluentPVC.DeepCopy()
+		fpvc.SetName(strings.Repeat("a", 70))
+		{
+			err := k8sClient.Create(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		defer func() {
+			err := k8sClient.Delete(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}()
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: fpvc.Name,
+		})
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+			name := mutPod.Labels[constants.PodLabelFluentPVCBindingName]
+			Expect(validation.IsDNS1123Label(name)).Should(BeEmpty())
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: name}, pvc)
+			Expect(err).Should(Succeed())
+			b := &fluentpvcv1alpha1.FluentPVCBinding{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: name}, b)
+			Expect(err).Should(Succeed())
+		}
+	})
+	It("should create the FluentPVCBindings with different names for the pods with the same generateName.", func() {
+		ctx := context.Background()
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+		Expect(err).Should(Succeed())
+
+		names := []string{}
+		for i := 0; i < 3; i++ {
+			pod := testPod.DeepCopy()
+			pod.SetName("")
+			pod.SetGenerateName(testPodName + "-")
+			pod.SetLabels(map[string]string{
+				constants.PodLabelFluentPVCName: testFluentPVCName,
+			})
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+			defer func() {
+				err := k8sClient.Delete(ctx, pod)
+				Expect(err).Should(Succeed())
+			}()
+
+			// NOTE: The webhook admits the pod before its name is generated.
+			podInWebhook := testPod.DeepCopy()
+			podInWebhook.SetName("")
+			podInWebhook.SetGenerateName(testPodName + "-")
+			expected, err := computeFluentPVCBindingName(fpvc, podInWebhook, testNamespace, int32(i))
+			Expect(err).Should(Succeed())
+			name := pod.Labels[constants.PodLabelFluentPVCBindingName]
+			Expect(name).Should(BeEquivalentTo(expected))
+			Expect(names).ShouldNot(ContainElement(name))
+			names = append(names, name)
+
+			b := &fluentpvcv1alpha1.FluentPVCBinding{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: name}, b)
+			Expect(err).Should(Succeed())
+			Expect(b.Spec.Pod.Name).Should(BeEquivalentTo(pod.Name))
+		}
+	})
+	It("should compute the same FluentPVCBinding name after a metadata-only update of the FluentPVC.", func() {
+		ctx := context.Background()
+		pod := testPod.DeepCopy()
+
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+		Expect(err).Should(Succeed())
+		name, err := computeFluentPVCBindingName(fpvc, pod, testNamespace, 0)
+		Expect(err).Should(Succeed())
+
+		fpvc.SetLabels(map[string]string{"test-label": "test"})
+		err = k8sClient.Update(ctx, fpvc)
+		Expect(err).Should(Succeed())
+		updated := &fluentpvcv1alpha1.FluentPVC{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, updated)
+		Expect(err).Should(Succeed())
+		Expect(updated.ResourceVersion).ShouldNot(BeEquivalentTo(fpvc.ResourceVersion))
+
+		updatedName, err := computeFluentPVCBindingName(updated, pod, testNamespace, 0)
+		Expect(err).Should(Succeed())
+		Expect(updatedName).Should(BeEquivalentTo(name))
+	})
+	It("should deny the Pod when the injected env conflicts with the Pod and the merge strategy is Fail.", func() {
+		ctx := context.Background()
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.CommonEnvs = []corev1.EnvVar{{Name: "TEST_ENV", Value: "fluent-pvc"}}
+			fpvc.Spec.MergeStrategies.Envs = fluentpvcv1alpha1.FluentPVCMergeStrategyFail
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "TEST_ENV", Value: "app"}}
+		bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+		err := k8sClient.List(ctx, bindings, client.InNamespace(testNamespace))
+		Expect(err).Should(Succeed())
+
+		err = k8sClient.Create(ctx, pod)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("env 'TEST_ENV' already exists in container '%s'", testContainerName))
+
+		bindingsAfter := &fluentpvcv1alpha1.FluentPVCBindingList{}
+		err = k8sClient.List(ctx, bindingsAfter, client.InNamespace(testNamespace))
+		Expect(err).Should(Succeed())
+		Expect(bindingsAfter.Items).Should(HaveLen(len(bindings.Items)))
+	})
+	It("should inject the volumeMount only into the target containers with the subPath.", func() {
+		ctx := context.Background()
+		const excludedContainerName = "istio-proxy"
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.TargetContainers.ExcludeNames = []string{excludedContainerName}
+			fpvc.Spec.PVCVolumeMountSubPaths = map[string]string{testSidecarContainerName: "sidecar"}
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:    excludedContainerName,
+			Command: []string{"echo", "test"},
+			Image:   "alpine",
+		})
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+			subPaths := map[string]*string{}
+			for _, c := range mutPod.Spec.Containers {
+				for _, vm := range c.VolumeMounts {
+					if vm.Name == testVolumeName {
+						subPath := vm.SubPath
+						subPaths[c.Name] = &subPath
+					}
+				}
+			}
+			Expect(subPaths).Should(HaveKey(testContainerName))
+			Expect(*subPaths[testContainerName]).Should(BeEmpty())
+			Expect(subPaths).Should(HaveKey(testSidecarContainerName))
+			Expect(*subPaths[testSidecarContainerName]).Should(BeEquivalentTo("sidecar"))
+			Expect(subPaths).ShouldNot(HaveKey(excludedContainerName))
+		}
+	})
+	It("should inject the volumeMount and the common envs into the init containers when injectInitContainers is true.", func() {
+		ctx := context.Background()
+		const (
+			initContainerName = "test-init-container"
+			commonEnvName     = "TEST_COMMON_ENV"
+		)
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.InjectInitContainers = true
+			fpvc.Spec.CommonEnvs = []corev1.EnvVar{{Name: commonEnvName, Value: "test"}}
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		pod.Spec.InitContainers = []corev1.Container{
+			{
+				Name:    initContainerName,
+				Command: []string{"echo", "test"},
+				Image:   "alpine",
+			},
+		}
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+			Expect(mutPod.Spec.InitContainers).Should(HaveLen(1))
+			c := mutPod.Spec.InitContainers[0]
+			Expect(c.Name).Should(Equal(initContainerName))
+			Expect(c.VolumeMounts).Should(ContainElement(corev1.VolumeMount{Name: testVolumeName, MountPath: testMountPath}))
+			Expect(c.Env).Should(ContainElement(corev1.EnvVar{Name: commonEnvName, Value: "test"}))
+		}
+	})
+	It("should inject the volumeMount and the common envs into the ephemeral containers added through the ephemeralcontainers subresource.", func() {
+		ctx := context.Background()
+		const (
+			ephemeralContainerName = "test-ephemeral-container"
+			commonEnvName          = "TEST_COMMON_ENV"
+		)
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.InjectEphemeralContainers = true
+			fpvc.Spec.CommonEnvs = []corev1.EnvVar{{Name: commonEnvName, Value: "test"}}
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		{
+			ec, err := clientset.CoreV1().Pods(pod.Namespace).GetEphemeralContainers(ctx, pod.Name, metav1.GetOptions{})
+			Expect(err).Should(Succeed())
+			ec.EphemeralContainers = append(ec.EphemeralContainers, corev1.EphemeralContainer{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Name:                     ephemeralContainerName,
+					Command:                  []string{"echo", "test"},
+					Image:                    "alpine",
+					ImagePullPolicy:          corev1.PullIfNotPresent,
+					TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+				},
+			})
+			_, err = clientset.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(ctx, pod.Name, ec, metav1.UpdateOptions{})
+			Expect(err).Should(Succeed())
+		}
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+			Expect(mutPod.Spec.EphemeralContainers).Should(HaveLen(1))
+			c := mutPod.Spec.EphemeralContainers[0]
+			Expect(c.Name).Should(Equal(ephemeralContainerName))
+			Expect(c.VolumeMounts).Should(ContainElement(corev1.VolumeMount{Name: testVolumeName, MountPath: testMountPath}))
+			Expect(c.Env).Should(ContainElement(corev1.EnvVar{Name: commonEnvName, Value: "test"}))
+		}
+	})
+	It("should merge podTemplateOverrides into the Pod.", func() {
+		ctx := context.Background()
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.PodTemplateOverrides = fluentpvcv1alpha1.FluentPVCPodTemplateOverrides{
+				Metadata: fluentpvcv1alpha1.FluentPVCPodTemplateOverridesMetadata{
+					Annotations: map[string]string{"backup.velero.io/backup-volumes-excludes": testVolumeName},
+				},
+				SecurityContext: &corev1.PodSecurityContext{FSGroup: pointer.Int64Ptr(2000)},
+			}
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsUser: pointer.Int64Ptr(1000)}
+		{
+			err := k8sClient.Create(ctx, pod)
+			Expect(err).Should(Succeed())
+		}
+		{
+			mutPod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+			Expect(err).Should(Succeed())
+			Expect(mutPod.Annotations).Should(HaveKeyWithValue("backup.velero.io/backup-volumes-excludes", testVolumeName))
+			Expect(mutPod.Spec.SecurityContext.FSGroup).ShouldNot(BeNil())
+			Expect(*mutPod.Spec.SecurityContext.FSGroup).Should(BeEquivalentTo(2000))
+			Expect(mutPod.Spec.SecurityContext.RunAsUser).ShouldNot(BeNil())
+			Expect(*mutPod.Spec.SecurityContext.RunAsUser).Should(BeEquivalentTo(1000))
+		}
+	})
+	It("should deny the Pod when the namespace is not allowed by FluentPVC.", func() {
+		ctx := context.Background()
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			fpvc.Spec.AllowedNamespaces = []string{"allowed-namespace"}
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		err := k8sClient.Create(ctx, pod)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("FluentPVC='%s' is not allowed in namespace='%s'.", testFluentPVCName, testNamespace))
+	})
+	It("should deny the Pod when the new PVC exceeds maxTotalStoragePerNamespace of FluentPVC.", func() {
+		ctx := context.Background()
+		fpvc := &fluentpvcv1alpha1.FluentPVC{}
+		{
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: testFluentPVCName}, fpvc)
+			Expect(err).Should(Succeed())
+			limit := resource.MustParse("500Mi")
+			fpvc.Spec.MaxTotalStoragePerNamespace = &limit
+			err = k8sClient.Update(ctx, fpvc)
+			Expect(err).Should(Succeed())
+		}
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		err := k8sClient.Create(ctx, pod)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("exceeds maxTotalStoragePerNamespace=500Mi."))
+	})
+	It("should delete the created PVC when the FluentPVCBinding creation is denied by the ResourceQuota.", func() {
+		ctx := context.Background()
+		const quotaNamespace = "test-quota-rollback"
+		bindingCount := corev1.ResourceName("count/fluentpvcbindings." + fluentpvcv1alpha1.GroupVersion.Group)
+		{
+			ns := &corev1.Namespace{}
+			ns.SetName(quotaNamespace)
+			err := k8sClient.Create(ctx, ns)
+			Expect(err).Should(Succeed())
+		}
+		quota := &corev1.ResourceQuota{}
+		quota.SetNamespace(quotaNamespace)
+		quota.SetName("test-quota")
+		quota.Spec.Hard = corev1.ResourceList{bindingCount: resource.MustParse("0")}
+		{
+			err := k8sClient.Create(ctx, quota)
+			Expect(err).Should(Succeed())
+			defer func() {
+				err := k8sClient.Delete(ctx, quota)
+				Expect(err).Should(Succeed())
+			}()
+			// NOTE: envtest does not run the resourcequota controller, so set the status by hand.
+			quota.Status.Hard = corev1.ResourceList{bindingCount: resource.MustParse("0")}
+			quota.Status.Used = corev1.ResourceList{bindingCount: resource.MustParse("0")}
+			err = k8sClient.Status().Update(ctx, quota)
+			Expect(err).Should(Succeed())
+		}
+
+		pod := testPod.DeepCopy()
+		pod.SetNamespace(quotaNamespace)
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName: testFluentPVCName,
+		})
+		err := k8sClient.Create(ctx, pod)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring(
+			"Cannot provision the PVC of FluentPVC='%s' because the ResourceQuota of namespace='%s' is exceeded.",
+			testFluentPVCName, quotaNamespace,
+		))
+
+		// NOTE: The PVC remains terminating because envtest does not run the pvc-protection controller.
+		pvcs := &corev1.PersistentVolumeClaimList{}
+		err = k8sClient.List(ctx, pvcs, client.InNamespace(quotaNamespace))
+		Expect(err).Should(Succeed())
+		for _, pvc := range pvcs.Items {
+			Expect(pvc.DeletionTimestamp).ShouldNot(BeNil())
+			Expect(pvc.Finalizers).ShouldNot(ContainElement(constants.PVCFinalizerName))
+		}
+		bindings := &fluentpvcv1alpha1.FluentPVCBindingList{}
+		err = k8sClient.List(ctx, bindings, client.InNamespace(quotaNamespace))
+		Expect(err).Should(Succeed())
+		Expect(bindings.Items).Should(BeEmpty())
+	})
 	It("should return a error when FluentPVC is not found.", func() {
 		ctx := context.Background()
 		pod := testPod.DeepCopy()
@@ -199,6 +743,22 @@ var _ = Describe("Pod Mutation Webhook", func() {
 				" the request: FluentPVC.fluent-pvc-operator.tech.zozo.com \"IS_NOT_FOUND\" not found",
 		))
 	})
+	It("should admit the Pod as degraded when FluentPVC is not found and the admission failure policy is Ignore.", func() {
+		ctx := context.Background()
+		pod := testPod.DeepCopy()
+		pod.SetLabels(map[string]string{
+			constants.PodLabelFluentPVCName:          "IS_NOT_FOUND",
+			constants.PodLabelAdmissionFailurePolicy: string(fluentpvcv1alpha1.FluentPVCAdmissionFailurePolicyIgnore),
+		})
+		err := k8sClient.Create(ctx, pod)
+		Expect(err).Should(Succeed())
+
+		mutPod := &corev1.Pod{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: pod.Name}, mutPod)
+		Expect(err).Should(Succeed())
+		Expect(mutPod.Labels[constants.PodLabelDegraded]).Should(BeEquivalentTo("Unmodified"))
+		Expect(mutPod.Spec.Containers).Should(HaveLen(1))
+	})
 	It("should not patch the Pod when the Pod is not a target of FluentPVC.", func() {
 		ctx := context.Background()
 		pod := testPod.DeepCopy()
@@ -217,3 +777,154 @@ var _ = Describe("Pod Mutation Webhook", func() {
 		Expect(volumeForPVC).To(BeNil())
 	})
 })
+
+func TestCheckNamespaceLimits(t *testing.T) {
+	const namespace = "test-namespace-limits"
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := fluentpvcv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	newFluentPVC := func(maxPVCs *int32, maxTotalStorage string) *fluentpvcv1alpha1.FluentPVC {
+		fpvc := &fluentpvcv1alpha1.FluentPVC{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-fluent-pvc"},
+			Spec: fluentpvcv1alpha1.FluentPVCSpec{
+				PVCSpecTemplate: corev1.PersistentVolumeClaimSpec{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+					},
+				},
+				MaxPVCsPerNamespace: maxPVCs,
+			},
+		}
+		if maxTotalStorage != "" {
+			limit := resource.MustParse(maxTotalStorage)
+			fpvc.Spec.MaxTotalStoragePerNamespace = &limit
+		}
+		return fpvc
+	}
+	newBinding := func(name string, finalizerPVCName string) *fluentpvcv1alpha1.FluentPVCBinding {
+		b := &fluentpvcv1alpha1.FluentPVCBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: fluentpvcv1alpha1.FluentPVCBindingSpec{
+				FluentPVC: fluentpvcv1alpha1.ObjectIdentity{Name: "test-fluent-pvc"},
+				PVC:       fluentpvcv1alpha1.ObjectIdentity{Name: name},
+			},
+		}
+		if finalizerPVCName != "" {
+			b.Status.FinalizerPVC = &fluentpvcv1alpha1.ObjectIdentity{Name: finalizerPVCName}
+		}
+		return b
+	}
+	newPVC := func(name, storage string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)},
+				},
+			},
+		}
+	}
+	cases := []struct {
+		name       string
+		fpvc       *fluentpvcv1alpha1.FluentPVC
+		objects    []client.Object
+		wantDenied bool
+	}{
+		{
+			name:    "no limits",
+			fpvc:    newFluentPVC(nil, ""),
+			objects: []client.Object{newBinding("b-1", ""), newPVC("b-1", "1Gi")},
+		},
+		{
+			name:    "pvcs below maxPVCsPerNamespace",
+			fpvc:    newFluentPVC(pointer.Int32Ptr(2), ""),
+			objects: []client.Object{newBinding("b-1", ""), newPVC("b-1", "1Gi")},
+		},
+		{
+			name: "pvcs reach maxPVCsPerNamespace",
+			fpvc: newFluentPVC(pointer.Int32Ptr(2), ""),
+			objects: []client.Object{
+				newBinding("b-1", ""), newPVC("b-1", "1Gi"),
+				newBinding("b-2", ""), newPVC("b-2", "1Gi"),
+			},
+			wantDenied: true,
+		},
+		{
+			name: "fluentpvcbinding without the pvc is not counted",
+			fpvc: newFluentPVC(pointer.Int32Ptr(2), ""),
+			objects: []client.Object{
+				newBinding("b-1", ""), newPVC("b-1", "1Gi"),
+				newBinding("b-2", ""),
+			},
+		},
+		{
+			name: "finalizer pvc is counted",
+			fpvc: newFluentPVC(pointer.Int32Ptr(2), ""),
+			objects: []client.Object{
+				newBinding("b-1", ""), newPVC("b-1", "1Gi"),
+				newBinding("b-2", "b-2-finalizer"), newPVC("b-2-finalizer", "1Gi"),
+			},
+			wantDenied: true,
+		},
+		{
+			name: "total storage reaches maxTotalStoragePerNamespace",
+			fpvc: newFluentPVC(nil, "3Gi"),
+			objects: []client.Object{
+				newBinding("b-1", ""), newPVC("b-1", "1Gi"),
+				newBinding("b-2", ""), newPVC("b-2", "1Gi"),
+			},
+		},
+		{
+			name: "total storage of the resized pvc exceeds maxTotalStoragePerNamespace",
+			fpvc: newFluentPVC(nil, "3Gi"),
+			objects: []client.Object{
+				newBinding("b-1", ""), newPVC("b-1", "1Gi"),
+				newBinding("b-2", ""), newPVC("b-2", "1536Mi"),
+			},
+			wantDenied: true,
+		},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			m := &podMutator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(c.objects...).Build()}
+			message, err := m.checkNamespaceLimits(context.Background(), c.fpvc, namespace)
+			if err != nil {
+				t.Fatalf("expected no error, but got %+v", err)
+			}
+			if denied := message != ""; denied != c.wantDenied {
+				t.Errorf("expected denied=%t, but got the message='%s'", c.wantDenied, message)
+			}
+		})
+	}
+}
+
+func TestIsQuotaExceeded(t *testing.T) {
+	gr := schema.GroupResource{Group: fluentpvcv1alpha1.GroupVersion.Group, Resource: "fluentpvcbindings"}
+	quotaErr := apierrors.NewForbidden(gr, "binding", xerrors.New(
+		"exceeded quota: test-quota, requested: count/fluentpvcbindings.fluent-pvc-operator.tech.zozo.com=1, used: count/fluentpvcbindings.fluent-pvc-operator.tech.zozo.com=0, limited: count/fluentpvcbindings.fluent-pvc-operator.tech.zozo.com=0",
+	))
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "exceeded quota", err: quotaErr, want: true},
+		{name: "wrapped exceeded quota", err: xerrors.Errorf("Unexpected error occurred.: %w", quotaErr), want: true},
+		{name: "forbidden by rbac", err: apierrors.NewForbidden(gr, "binding", xerrors.New("access denied")), want: false},
+		{name: "not forbidden", err: apierrors.NewBadRequest("exceeded quota"), want: false},
+		{name: "not api error", err: xerrors.New("exceeded quota"), want: false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if got := isQuotaExceeded(c.err); got != c.want {
+				t.Errorf("expected %t, but got %t", c.want, got)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	//+kubebuilder:scaffold:imports
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -35,6 +36,7 @@ import (
 
 var cfg *rest.Config
 var k8sClient client.Client
+var clientset kubernetes.Interface
 var testEnv *envtest.Environment
 var ctx context.Context
 var cancel context.CancelFunc
@@ -60,6 +62,8 @@ var _ = BeforeSuite(func() {
 			Paths: []string{filepath.Join("..", "config", "webhook")},
 		},
 	}
+	// NOTE: The ephemeralcontainers subresource is served only if the feature gate is enabled before k8s 1.23.
+	testEnv.ControlPlane.GetAPIServer().Configure().Append("feature-gates", "EphemeralContainers=true")
 
 	cfg, err := testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
@@ -87,6 +91,10 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	// NOTE: controller-runtime client does not support subresources other than status.
+	clientset, err = kubernetes.NewForConfig(cfg)
+	Expect(err).NotTo(HaveOccurred())
+
 	// start webhook server using Manager
 	webhookInstallOptions := &testEnv.WebhookInstallOptions
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
@@ -105,6 +113,9 @@ var _ = BeforeSuite(func() {
 	err = SetupFluentPVCWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = SetupFluentPVCBindingWebhookWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
 	//+kubebuilder:scaffold:webhook
 
 	go func() {